@@ -2,7 +2,13 @@ module github.com/everettraven/cade
 
 go 1.18
 
-require gopkg.in/yaml.v2 v2.4.0
+require (
+	github.com/BurntSushi/toml v1.4.0
+	github.com/fsnotify/fsnotify v1.6.0
+	golang.org/x/crypto v0.9.0
+	golang.org/x/sys v0.8.0
+	gopkg.in/yaml.v2 v2.4.0
+)
 
 require (
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
@@ -0,0 +1,290 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// modeBits are the bits of the file modes in an archive that are kept when it is extracted
+const modeBits = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+// Entry is a path on the host that is added to an archive
+type Entry struct {
+	// The path on the host
+	Path string
+	// The name of the top level entry of the archive the path is added as
+	Name string
+	// Whether or not only the directory at the path is added without what is in it
+	NoRecurse bool
+}
+
+// Owner is the user and group that own the files in an archive
+type Owner struct {
+	Uid int
+	Gid int
+}
+
+// Write writes a tar archive of the entries to the writer. Directories are added with
+// everything in them unless NoRecurse is set and symbolic links are added as links. Files keep their permissions
+// and modification times and are owned by the owner. Sockets can't be archived so they are
+// skipped. Returns an error if any occur during the process
+func Write(w io.Writer, entries []Entry, owner Owner) error {
+	tw := tar.NewWriter(w)
+
+	for _, entry := range entries {
+		err := filepath.Walk(entry.Path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.Mode()&os.ModeSocket != 0 {
+				return nil
+			}
+
+			if entry.NoRecurse && info.IsDir() && file != entry.Path {
+				return filepath.SkipDir
+			}
+
+			rel, err := filepath.Rel(entry.Path, file)
+			if err != nil {
+				return err
+			}
+
+			link := ""
+			if info.Mode()&os.ModeSymlink != 0 {
+				link, err = os.Readlink(file)
+				if err != nil {
+					return err
+				}
+			}
+
+			header, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				return err
+			}
+
+			header.Name = path.Join(entry.Name, filepath.ToSlash(rel))
+			// the writer rounds to the nearest second, which would make
+			// the copy newer than the file half of the time
+			header.ModTime = info.ModTime().Truncate(time.Second)
+			if info.IsDir() {
+				header.Name += "/"
+			}
+			header.Uid, header.Gid = owner.Uid, owner.Gid
+			header.Uname, header.Gname = "", ""
+
+			err = tw.WriteHeader(header)
+			if err != nil {
+				return err
+			}
+
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			_, err = io.Copy(tw, f)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("encountered an error archiving `%s`: %w", entry.Path, err)
+		}
+	}
+
+	return tw.Close()
+}
+
+// Extract extracts the tar archive read from the reader into the directory, which is created
+// if it doesn't exist. The top level entry of the archive is renamed to root, a slash separated
+// path relative to the directory, unless it is empty. Symbolic links are not written through.
+// Files keep the permissions and modification times from the archive and are owned by the
+// current user. Returns an error if any occur during the process
+func Extract(r io.Reader, dir string, root string) error {
+	tr := tar.NewReader(r)
+
+	type dirInfo struct {
+		path    string
+		mode    os.FileMode
+		modTime time.Time
+	}
+	// the permissions of directories are set once everything is extracted
+	// so directories without write permissions can be extracted into
+	dirs := []dirInfo{}
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("encountered an error reading the archive: %w", err)
+		}
+
+		rel := localPath(renameRoot(header.Name, root))
+		target := filepath.Join(dir, rel)
+		mode := header.FileInfo().Mode() & modeBits
+
+		// an earlier entry can be a symbolic link to outside of the directory
+		err = CheckParents(dir, rel)
+		if err != nil {
+			return fmt.Errorf("encountered an error extracting `%s`: %w", header.Name, err)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = replaceSymlink(target)
+			if err == nil {
+				err = os.MkdirAll(target, 0777)
+			}
+			if err == nil {
+				dirs = append(dirs, dirInfo{path: target, mode: mode, modTime: header.ModTime})
+			}
+		case tar.TypeReg:
+			err = extractFile(tr, target, mode, header.ModTime)
+		case tar.TypeSymlink:
+			err = replace(target, func() error {
+				return os.Symlink(header.Linkname, target)
+			})
+		case tar.TypeLink:
+			linkedRel := localPath(renameRoot(header.Linkname, root))
+			linked := filepath.Join(dir, linkedRel)
+			err = CheckParents(dir, linkedRel)
+			if err == nil {
+				err = replace(target, func() error {
+					return os.Link(linked, target)
+				})
+			}
+		default:
+			// devices and fifos are not copied
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("encountered an error extracting `%s`: %w", header.Name, err)
+		}
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		err := os.Chmod(dirs[i].path, dirs[i].mode)
+		if err != nil {
+			return fmt.Errorf("encountered an error setting the permissions of `%s`: %w", dirs[i].path, err)
+		}
+		os.Chtimes(dirs[i].path, dirs[i].modTime, dirs[i].modTime)
+	}
+
+	return nil
+}
+
+// extractFile writes the file with the contents read from the reader
+func extractFile(r io.Reader, target string, mode os.FileMode, modTime time.Time) error {
+	return replace(target, func() error {
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(f, r)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+
+		// the mode is set after writing so the umask doesn't apply
+		// and read-only files can be written
+		err = os.Chmod(target, mode)
+		if err != nil {
+			return err
+		}
+
+		return os.Chtimes(target, modTime, modTime)
+	})
+}
+
+// replace creates the file at the target path with create after removing the file that is there, if any.
+// Directories are not removed so extracting a file over a directory is an error
+func replace(target string, create func() error) error {
+	err := os.MkdirAll(filepath.Dir(target), 0777)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Lstat(target)
+	if err == nil && !info.IsDir() {
+		err = os.Remove(target)
+		if err != nil {
+			return err
+		}
+	} else if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return create()
+}
+
+// replaceSymlink removes the file at the target path if it is a symbolic link
+// so a directory can be created in its place instead of through it
+func replaceSymlink(target string) error {
+	info, err := os.Lstat(target)
+	if err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return os.Remove(target)
+	} else if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// CheckParents returns an error if any of the directories between dir and the relative
+// host path is a symbolic link, since writing through it could write outside of dir
+func CheckParents(dir string, rel string) error {
+	parent := dir
+	elements := strings.Split(rel, string(filepath.Separator))
+	for _, element := range elements[:len(elements)-1] {
+		parent = filepath.Join(parent, element)
+
+		info, err := os.Lstat(parent)
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("refusing to write through the symbolic link `%s`", parent)
+		}
+	}
+
+	return nil
+}
+
+// renameRoot replaces the first element of the name with the root unless it is empty
+func renameRoot(name string, root string) string {
+	if root == "" {
+		return name
+	}
+
+	_, rest, found := strings.Cut(strings.TrimPrefix(name, "/"), "/")
+	if !found {
+		return root
+	}
+
+	return root + "/" + rest
+}
+
+// localPath converts the name of an entry to a relative host path
+// that can't point outside of the directory the archive is extracted into
+func localPath(name string) string {
+	return filepath.FromSlash(strings.TrimPrefix(path.Clean("/"+name), "/"))
+}
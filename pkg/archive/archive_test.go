@@ -0,0 +1,185 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tarEntry is an entry of an archive written by writeTar
+type tarEntry struct {
+	name     string
+	typeflag byte
+	linkname string
+	content  string
+}
+
+// writeTar returns an archive with the entries in order
+func writeTar(t *testing.T, entries []tarEntry) *bytes.Buffer {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, entry := range entries {
+		header := &tar.Header{
+			Name:     entry.name,
+			Typeflag: entry.typeflag,
+			Linkname: entry.linkname,
+			Mode:     0644,
+			Size:     int64(len(entry.content)),
+		}
+		if entry.typeflag == tar.TypeDir {
+			header.Mode = 0755
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf
+}
+
+func TestExtractDoesNotWriteOutsideDir(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		entries func(outside string) []tarEntry
+		wantErr bool
+	}{
+		{
+			name: "file through a symbolic link to a directory outside",
+			entries: func(outside string) []tarEntry {
+				return []tarEntry{
+					{name: "d", typeflag: tar.TypeSymlink, linkname: outside},
+					{name: "d/.bashrc", typeflag: tar.TypeReg, content: "evil"},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "file through a nested symbolic link",
+			entries: func(outside string) []tarEntry {
+				return []tarEntry{
+					{name: "a/", typeflag: tar.TypeDir},
+					{name: "a/d", typeflag: tar.TypeSymlink, linkname: outside},
+					{name: "a/d/.bashrc", typeflag: tar.TypeReg, content: "evil"},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "file through a relative symbolic link",
+			entries: func(outside string) []tarEntry {
+				return []tarEntry{
+					{name: "d", typeflag: tar.TypeSymlink, linkname: "../outside"},
+					{name: "d/.bashrc", typeflag: tar.TypeReg, content: "evil"},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "hard link through a symbolic link",
+			entries: func(outside string) []tarEntry {
+				return []tarEntry{
+					{name: "d", typeflag: tar.TypeSymlink, linkname: outside},
+					{name: "l", typeflag: tar.TypeLink, linkname: "d/.bashrc"},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "directory over a symbolic link",
+			entries: func(outside string) []tarEntry {
+				return []tarEntry{
+					{name: "d", typeflag: tar.TypeSymlink, linkname: outside},
+					{name: "d/", typeflag: tar.TypeDir},
+					{name: "d/.bashrc", typeflag: tar.TypeReg, content: "evil"},
+				}
+			},
+		},
+		{
+			name: "file over a symbolic link",
+			entries: func(outside string) []tarEntry {
+				return []tarEntry{
+					{name: "f", typeflag: tar.TypeSymlink, linkname: filepath.Join(outside, ".bashrc")},
+					{name: "f", typeflag: tar.TypeReg, content: "evil"},
+				}
+			},
+		},
+		{
+			name: "parent directory references",
+			entries: func(outside string) []tarEntry {
+				return []tarEntry{
+					{name: "../outside/.bashrc", typeflag: tar.TypeReg, content: "evil"},
+				}
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			base := t.TempDir()
+			dir := filepath.Join(base, "dir")
+			outside := filepath.Join(base, "outside")
+			if err := os.Mkdir(outside, 0755); err != nil {
+				t.Fatal(err)
+			}
+			bashrc := filepath.Join(outside, ".bashrc")
+			if err := os.WriteFile(bashrc, []byte("original"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := Extract(writeTar(t, tc.entries(outside)), dir, "")
+			if tc.wantErr && err == nil {
+				t.Error("expected an error extracting the archive")
+			} else if !tc.wantErr && err != nil {
+				t.Errorf("unexpected error extracting the archive: %v", err)
+			}
+
+			content, err := os.ReadFile(bashrc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != "original" {
+				t.Errorf("the file outside of the directory was overwritten with %q", content)
+			}
+
+			entries, err := os.ReadDir(outside)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("expected only .bashrc outside of the directory, found %d files", len(entries))
+			}
+		})
+	}
+}
+
+func TestExtractRenamesRoot(t *testing.T) {
+	dir := t.TempDir()
+	archive := writeTar(t, []tarEntry{
+		{name: "src/", typeflag: tar.TypeDir},
+		{name: "src/sub/", typeflag: tar.TypeDir},
+		{name: "src/sub/f", typeflag: tar.TypeReg, content: "content"},
+		{name: "src/link", typeflag: tar.TypeSymlink, linkname: "sub/f"},
+	})
+
+	err := Extract(archive, dir, "dest")
+	if err != nil {
+		t.Fatalf("unexpected error extracting the archive: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "dest", "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "content" {
+		t.Errorf("expected the extracted file to contain %q, got %q", "content", content)
+	}
+}
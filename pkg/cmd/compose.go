@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/everettraven/cade/pkg/config"
+	"github.com/everettraven/cade/pkg/containerutil"
+	"github.com/everettraven/cade/pkg/workspace"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// upCompose brings up the compose project of the workspace. The compose service used
+// as the workspace container is named and labeled like any other workspace container
+// so the rest of the commands don't need to know it was created by compose
+func upCompose(w io.Writer, workspaceName string, workspaceConfig *config.WorkspaceConfig, containerUtil containerutil.ContainerUtil) error {
+	compose := workspaceConfig.Compose
+	if compose.File == "" || compose.Service == "" {
+		return fmt.Errorf("the compose config must set both the file and the service")
+	}
+
+	project := workspace.ComposeProjectName(workspaceName)
+
+	labels := workspace.Labels(workspaceName)
+	labels[workspace.ComposeProjectLabel] = project
+	if profile != "" {
+		labels[workspace.ProfileLabel] = profile
+	}
+
+	service := map[string]interface{}{
+		"container_name": workspace.ContainerName(workspaceName),
+		"labels":         labels,
+	}
+
+	if workspaceConfig.WorkdirMode == config.WorkdirModeSync {
+		return fmt.Errorf("workdir_mode sync is not supported for compose workspaces")
+	}
+
+	if workspaceConfig.Healthcheck != nil {
+		return fmt.Errorf("healthcheck is not supported for compose workspaces. Set the healthcheck of the compose service %s instead", compose.Service)
+	}
+
+	if workspaceConfig.UserMapping != "" {
+		err := workspaceConfig.UserMapping.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if workspaceConfig.UserMapping == config.UserMappingAuto {
+		user, err := hostUser()
+		if err != nil {
+			return err
+		}
+		service["user"] = user
+
+		if workspaceConfig.RemapImageUser {
+			fmt.Fprintln(w, "The image user is not remapped for compose workspaces. Only the container user is set to", user)
+		}
+	}
+
+	forwardVolumes, forwardEnv, forwardLabels, err := forwarding(w, workspaceName, workspaceConfig)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range forwardLabels {
+		labels[key] = value
+	}
+
+	if len(forwardEnv) > 0 {
+		service["environment"] = forwardEnv
+	}
+
+	volumes := []string{}
+	mounts := []containerutil.Volume{}
+	mounts = append(mounts, workspaceConfig.Volumes...)
+	mounts = append(mounts, forwardVolumes...)
+	for _, volume := range mounts {
+		spec := fmt.Sprintf("%s:%s", volume.HostPath, volume.MountPath)
+		if volume.ReadOnly {
+			spec += ":ro"
+		}
+		volumes = append(volumes, spec)
+	}
+
+	if len(volumes) > 0 {
+		service["volumes"] = volumes
+	}
+
+	upArgs := []string{}
+	if build {
+		upArgs = append(upArgs, "--build")
+	}
+
+	if workspaceConfig.Workdir != "" {
+		workspaceDir := userSettings.WorkspaceDir(workspaceName)
+		workdirVolume := containerutil.Volume{HostPath: workspaceDir, MountPath: workspaceConfig.Workdir}
+
+		if _, err := os.Stat(workspaceDir); os.IsNotExist(err) {
+			err = seedComposeWorkdir(w, workspaceName, project, compose, service, workdirVolume, upArgs, containerUtil)
+			if err != nil {
+				return err
+			}
+		} else if err != nil {
+			return fmt.Errorf("encountered an error checking if directory `%s` already exists: %w", workspaceDir, err)
+		}
+
+		service["volumes"] = append([]string{fmt.Sprintf("%s:%s", workspaceDir, workspaceConfig.Workdir)}, volumes...)
+	}
+
+	overrideFile, err := writeComposeOverride(compose.Service, service)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(overrideFile)
+
+	fmt.Fprintln(w, "Bringing up the compose project", project, "from", compose.File, "(this could take some time...)")
+	out, err := containerUtil.ComposeUp(containerutil.ComposeProject{
+		Name:  project,
+		Files: []string{compose.File, overrideFile},
+	}, upArgs...)
+	if err != nil {
+		return fmt.Errorf("encountered an error bringing up the compose project: %w | out: %s", err, out)
+	}
+	upOutput.Write(out)
+
+	installDotfiles(w, workspaceName, containerUtil)
+
+	if wait {
+		err = waitHealthy(w, workspaceName, waitTimeout, containerUtil)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(w, "Workspace ready! The workspace name is", workspaceName, "and the workspace container is the compose service", compose.Service)
+	return nil
+}
+
+// seedComposeWorkdir creates the compose project without starting it and copies the working
+// directory of the workspace service to the host, like `cade up` does for other workspaces.
+// Returns an error if any occur during the process
+func seedComposeWorkdir(w io.Writer, workspaceName string, project string, compose *config.ComposeConfig, service map[string]interface{}, volume containerutil.Volume, upArgs []string, containerUtil containerutil.ContainerUtil) error {
+	baseWorkspaceDir := userSettings.WorkspaceRoot
+	fmt.Fprintln(w, "Ensuring the", baseWorkspaceDir, "directory is created")
+	err := os.MkdirAll(baseWorkspaceDir, 0777)
+	if err != nil {
+		return fmt.Errorf("encountered an error ensuring the directory `%s` exists: %w", baseWorkspaceDir, err)
+	}
+
+	// the service is created without the working directory mounted so it has the files of the image
+	overrideFile, err := writeComposeOverride(compose.Service, service)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(overrideFile)
+
+	fmt.Fprintln(w, "Creating the compose project", project, "to copy the working directory of the service", compose.Service)
+	out, err := containerUtil.ComposeUp(containerutil.ComposeProject{
+		Name:  project,
+		Files: []string{compose.File, overrideFile},
+	}, append([]string{"--no-start"}, upArgs...)...)
+	if err != nil {
+		return fmt.Errorf("encountered an error creating the compose project: %w | out: %s", err, out)
+	}
+	upOutput.Write(out)
+
+	container, _, err := containerUtil.InspectContainer(workspace.ContainerName(workspaceName))
+	if err != nil {
+		return fmt.Errorf("encountered an error getting the workspace container: %w", err)
+	}
+
+	fmt.Fprintln(w, "Copying files from container to workspace directory")
+	out, err = containerUtil.CopyToHost(*container, volume)
+	if err != nil {
+		return fmt.Errorf("encountered an error copying files from container to host: %w | out: %s", err, out)
+	}
+
+	return nil
+}
+
+// writeComposeOverride writes a compose file that overrides the service with the provided
+// name to a temporary file and returns its path. Returns an error if any occur during the process
+func writeComposeOverride(serviceName string, service map[string]interface{}) (string, error) {
+	override, err := yaml.Marshal(map[string]interface{}{
+		"services": map[string]interface{}{
+			serviceName: service,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("encountered an error generating the compose override file: %w", err)
+	}
+
+	overrideFile, err := os.CreateTemp("", "cade-compose-*.yaml")
+	if err != nil {
+		return "", fmt.Errorf("encountered an error creating the compose override file: %w", err)
+	}
+
+	_, err = overrideFile.Write(override)
+	if err == nil {
+		err = overrideFile.Close()
+	} else {
+		overrideFile.Close()
+	}
+	if err != nil {
+		os.Remove(overrideFile.Name())
+		return "", fmt.Errorf("encountered an error writing the compose override file: %w", err)
+	}
+
+	return overrideFile.Name(), nil
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/everettraven/cade/pkg/config"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "commands for working with workspace configs",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var configRenderCmd = &cobra.Command{
+	Use:   "render [CONFIG | -]",
+	Short: "prints the fully resolved workspace config",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return render(args[0])
+	},
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "prints the JSON Schema of the workspace config",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return schema()
+	},
+}
+
+// setValues are the config variable values set with --set key=value or --set key
+var setValues []string
+
+// profile is the name of the config profile to apply
+var profile string
+
+func init() {
+	configRenderCmd.Flags().StringArrayVar(&setValues, "set", nil, "set a config variable, e.g. --set user=me, or pass it from the environment with --set user. Can be repeated")
+	configRenderCmd.Flags().StringVarP(&profile, "profile", "P", "", "the config profile to apply")
+	configCmd.AddCommand(configRenderCmd)
+	configCmd.AddCommand(configSchemaCmd)
+}
+
+func render(configPath string) error {
+	set, err := parseSetValues(setValues)
+	if err != nil {
+		return err
+	}
+
+	workspaceConfig, err := config.ParseWorkspaceConfig(configPath, config.ParseOptions{Set: set, Profile: profile})
+	if err != nil {
+		return fmt.Errorf("encountered an error getting the cade config: %w", err)
+	}
+
+	out, err := yaml.Marshal(workspaceConfig)
+	if err != nil {
+		return fmt.Errorf("encountered an error rendering the cade config: %w", err)
+	}
+
+	fmt.Print(string(out))
+	return nil
+}
+
+func schema() error {
+	out, err := json.MarshalIndent(config.GenerateSchema(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("encountered an error generating the config schema: %w", err)
+	}
+
+	fmt.Println(string(out))
+	return nil
+}
+
+// parseSetValues parses key=value pairs into a map. A key without
+// a value is set to the value of the environment variable with its name
+func parseSetValues(values []string) (map[string]string, error) {
+	set := map[string]string{}
+	for _, value := range values {
+		key, val, ok := strings.Cut(value, "=")
+		if key == "" {
+			return nil, fmt.Errorf("invalid --set value %q. must be in the format key=value or key", value)
+		}
+
+		if !ok {
+			val, ok = os.LookupEnv(key)
+			if !ok {
+				return nil, fmt.Errorf("the environment variable %s passed with --set is not set", key)
+			}
+		}
+		set[key] = val
+	}
+
+	return set, nil
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// stdin is shared between prompts so input buffered
+// by one prompt is not lost to the next one
+var stdin = bufio.NewReader(os.Stdin)
+
+// confirm prompts the user with the provided question and
+// returns whether or not they answered yes. Anything other
+// than an explicit yes, including no input, is treated as no
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N]: ", question)
+
+	answer, err := stdin.ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Println()
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// ask prompts the user with the provided question and returns
+// their answer, or the default answer if they didn't provide one
+func ask(question string, defaultAnswer string) string {
+	fmt.Printf("%s [%s]: ", question, defaultAnswer)
+
+	answer, _ := stdin.ReadString('\n')
+	if answer = strings.TrimSpace(answer); answer != "" {
+		return answer
+	}
+
+	return defaultAnswer
+}
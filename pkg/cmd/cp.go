@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/everettraven/cade/pkg/archive"
+	"github.com/everettraven/cade/pkg/containerutil"
+	"github.com/everettraven/cade/pkg/workspace"
+	"github.com/spf13/cobra"
+)
+
+// matchPathsScript prints the absolute path of every file in the container matching the
+// pattern passed as the first argument, one per line. Relative patterns are matched
+// against the working directory of the container, which is the workspace workdir
+const matchPathsScript = `IFS='
+'
+for match in $1; do
+	if [ -e "$match" ] || [ -L "$match" ]; then
+		case "$match" in
+			/*) printf '%s\n' "$match" ;;
+			*) printf '%s\n' "$PWD/$match" ;;
+		esac
+	fi
+done
+`
+
+// destinationScript prints the uid and gid of the container user, whether the path passed
+// as the first argument is a directory and the absolute path of it, one per line
+const destinationScript = `id -u
+id -g
+if [ -d "$1" ]; then
+	echo dir
+else
+	echo none
+fi
+case "$1" in
+	/*) printf '%s\n' "$1" ;;
+	*) printf '%s\n' "$PWD/$1" ;;
+esac
+`
+
+var cpCmd = &cobra.Command{
+	Use:   "cp SRC... DEST",
+	Short: "copies files and directories between the host and a workspace. Paths in the workspace are written as WORKSPACE:PATH and may be globs",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		containerUtil, err := newContainerUtil()
+		if err != nil {
+			return err
+		}
+		return cp(args[:len(args)-1], args[len(args)-1], containerUtil)
+	},
+}
+
+func cp(sources []string, dest string, containerUtil containerutil.ContainerUtil) error {
+	destWorkspace, destPath, toWorkspace := splitWorkspacePath(dest)
+
+	sourceWorkspace := ""
+	sourcePaths := []string{}
+	for _, source := range sources {
+		workspaceName, sourcePath, fromWorkspace := splitWorkspacePath(source)
+		if fromWorkspace == toWorkspace {
+			return fmt.Errorf("cade cp copies between the host and a workspace so either the sources or the destination must be a WORKSPACE:PATH")
+		}
+
+		if fromWorkspace && sourceWorkspace != "" && workspaceName != sourceWorkspace {
+			return fmt.Errorf("all of the sources must be in the same workspace")
+		}
+
+		if !fromWorkspace {
+			sourcePath = source
+		}
+
+		sourceWorkspace = workspaceName
+		sourcePaths = append(sourcePaths, sourcePath)
+	}
+
+	if toWorkspace {
+		return copyToWorkspace(sourcePaths, destWorkspace, destPath, containerUtil)
+	}
+
+	return copyFromWorkspace(sourceWorkspace, sourcePaths, dest, containerUtil)
+}
+
+// splitWorkspacePath splits a WORKSPACE:PATH argument into the workspace name and the path.
+// Returns false if the argument is a host path, which is the case if the part before the
+// colon has a path separator or, on Windows, is a drive letter
+func splitWorkspacePath(arg string) (string, string, bool) {
+	workspaceName, workspacePath, found := strings.Cut(arg, ":")
+	if !found || workspaceName == "" || strings.ContainsAny(workspaceName, `/\`) {
+		return "", "", false
+	}
+
+	if runtime.GOOS == "windows" && len(workspaceName) == 1 {
+		return "", "", false
+	}
+
+	return workspaceName, workspacePath, true
+}
+
+// copyToWorkspace copies the host files matching the sources into the running workspace container
+func copyToWorkspace(sources []string, workspaceName string, dest string, containerUtil containerutil.ContainerUtil) error {
+	entries := []archive.Entry{}
+	for _, source := range sources {
+		matches, err := filepath.Glob(source)
+		if err != nil {
+			return fmt.Errorf("encountered an error matching `%s`: %w", source, err)
+		}
+
+		if len(matches) == 0 {
+			_, err := os.Lstat(source)
+			if err != nil {
+				return fmt.Errorf("no files match `%s`: %w", source, err)
+			}
+			matches = []string{source}
+		}
+
+		for _, match := range matches {
+			abs, err := filepath.Abs(match)
+			if err != nil {
+				return fmt.Errorf("encountered an error getting the absolute path of `%s`: %w", match, err)
+			}
+			entries = append(entries, archive.Entry{Path: abs, Name: filepath.Base(abs)})
+		}
+	}
+
+	container := workspace.ContainerName(workspaceName)
+	if dest == "" {
+		dest = "."
+	}
+
+	out := &bytes.Buffer{}
+	err := containerUtil.Exec(containerutil.ExecOptions{Stdout: out}, container, "sh", "-c", destinationScript, "sh", dest)
+	if err != nil {
+		return fmt.Errorf("encountered an error checking the destination in the workspace: %w", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		return fmt.Errorf("encountered an error checking the destination in the workspace: unexpected output %q", out.String())
+	}
+
+	uid, uidErr := strconv.Atoi(lines[0])
+	gid, gidErr := strconv.Atoi(lines[1])
+	if uidErr != nil || gidErr != nil {
+		return fmt.Errorf("encountered an error getting the user of the workspace container: unexpected output %q", out.String())
+	}
+
+	dir := path.Clean(lines[3])
+	if lines[2] != "dir" {
+		if len(entries) > 1 {
+			return fmt.Errorf("the destination `%s` must be an existing directory when copying more than one file", dest)
+		}
+
+		// a single source is copied to the destination path rather than into it
+		entries[0].Name = path.Base(dir)
+		dir = path.Dir(dir)
+	}
+
+	// the files are owned by the workspace user like the files it creates
+	return streamArchive(func(w io.Writer) error {
+		return archive.Write(w, entries, archive.Owner{Uid: uid, Gid: gid})
+	}, func(r io.Reader) error {
+		err := containerUtil.CopyArchiveToContainer(container, dir, r)
+		if err != nil {
+			return fmt.Errorf("encountered an error copying into the workspace container: %w", err)
+		}
+		return nil
+	})
+}
+
+// copyFromWorkspace copies the files in the running workspace container matching the sources to the host
+func copyFromWorkspace(workspaceName string, sources []string, dest string, containerUtil containerutil.ContainerUtil) error {
+	container := workspace.ContainerName(workspaceName)
+
+	matches := []string{}
+	for _, source := range sources {
+		out := &bytes.Buffer{}
+		err := containerUtil.Exec(containerutil.ExecOptions{Stdout: out}, container, "sh", "-c", matchPathsScript, "sh", source)
+		if err != nil {
+			return fmt.Errorf("encountered an error matching `%s` in the workspace: %w", source, err)
+		}
+
+		found := false
+		for _, match := range strings.Split(out.String(), "\n") {
+			if match != "" {
+				matches = append(matches, path.Clean(match))
+				found = true
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("no files in the workspace match `%s`", source)
+		}
+	}
+
+	info, err := os.Stat(dest)
+	destIsDir := err == nil && info.IsDir()
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("encountered an error checking the destination `%s`: %w", dest, err)
+	}
+
+	if !destIsDir && len(matches) > 1 {
+		return fmt.Errorf("the destination `%s` must be an existing directory when copying more than one file", dest)
+	}
+
+	dir, root := dest, ""
+	if !destIsDir {
+		// a single source is copied to the destination path rather than into it
+		dir, root = filepath.Dir(dest), filepath.Base(dest)
+	}
+
+	for _, match := range matches {
+		err := streamArchive(func(w io.Writer) error {
+			err := containerUtil.CopyArchiveFromContainer(container, match, w)
+			if err != nil {
+				return fmt.Errorf("encountered an error copying `%s` from the workspace container: %w", match, err)
+			}
+			return nil
+		}, func(r io.Reader) error {
+			return archive.Extract(r, dir, root)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// streamArchive writes an archive with write while it is read by read. The error of
+// write is returned first since read fails when the archive is incomplete
+func streamArchive(write func(w io.Writer) error, read func(r io.Reader) error) error {
+	reader, writer := io.Pipe()
+
+	writeErr := make(chan error, 1)
+	go func() {
+		err := write(writer)
+		writer.CloseWithError(err)
+		writeErr <- err
+	}()
+
+	err := read(reader)
+	// unblocks write if read returned before reading everything
+	reader.CloseWithError(io.ErrClosedPipe)
+
+	if wErr := <-writeErr; wErr != nil && !errors.Is(wErr, io.ErrClosedPipe) {
+		return wErr
+	}
+
+	return err
+}
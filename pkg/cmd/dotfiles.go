@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/everettraven/cade/pkg/containerutil"
+	"github.com/everettraven/cade/pkg/workspace"
+	"github.com/spf13/cobra"
+)
+
+// dotfilesStagingDir is where the dotfiles are copied in the container
+// before they are moved into the home directory of the container user
+const dotfilesStagingDir = "/tmp/cade-dotfiles"
+
+// installDotfilesScript moves the staged dotfiles to ~/.dotfiles and runs the install command,
+// passed as the first argument, in it. Without an install command the first common install
+// script is run, or the dotfiles are linked into the home directory if there is none
+const installDotfilesScript = `set -e
+rm -rf "$HOME/.dotfiles"
+mkdir -p "$HOME"
+cp -R ` + dotfilesStagingDir + ` "$HOME/.dotfiles"
+cd "$HOME/.dotfiles"
+
+if [ -n "$1" ]; then
+	exec sh -c "$1"
+fi
+
+for script in install.sh install bootstrap.sh bootstrap setup.sh setup; do
+	if [ -f "$script" ]; then
+		chmod +x "$script"
+		exec "./$script"
+	fi
+done
+
+for file in .[!.]*; do
+	if [ -e "$file" ] && [ "$file" != .git ]; then
+		ln -sfn "$HOME/.dotfiles/$file" "$HOME/$file"
+	fi
+done
+`
+
+var dotfilesCmd = &cobra.Command{
+	Use:   "dotfiles",
+	Short: "commands for working with the dotfiles installed in workspaces",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var dotfilesApplyCmd = &cobra.Command{
+	Use:   "apply [WORKSPACE]",
+	Short: "installs the dotfiles in the workspace specified again",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		containerUtil, err := newContainerUtil()
+		if err != nil {
+			return err
+		}
+		return applyDotfiles(os.Stdout, args[0], containerUtil)
+	},
+}
+
+func init() {
+	dotfilesCmd.AddCommand(dotfilesApplyCmd)
+}
+
+// installDotfiles installs the dotfiles in a newly created workspace if any are configured.
+// Failing to install them is not an error because the workspace is usable without them
+func installDotfiles(w io.Writer, workspaceName string, containerUtil containerutil.ContainerUtil) {
+	if userSettings.Dotfiles.Source == "" {
+		return
+	}
+
+	err := applyDotfiles(w, workspaceName, containerUtil)
+	if err != nil {
+		fmt.Fprintln(w, "WARNING: failed to install the dotfiles:", err)
+		fmt.Fprintln(w, "Run `cade dotfiles apply", workspaceName+"` to try again")
+	}
+}
+
+// applyDotfiles copies the dotfiles into the workspace container and runs their install command
+func applyDotfiles(w io.Writer, workspaceName string, containerUtil containerutil.ContainerUtil) error {
+	dotfiles := userSettings.Dotfiles
+	if dotfiles.Source == "" {
+		return fmt.Errorf("no dotfiles are configured. Set dotfiles.source in the cade settings file")
+	}
+
+	dir := dotfiles.Source
+	if isGitSource(dotfiles.Source) {
+		tmp, err := os.MkdirTemp("", "cade-dotfiles-")
+		if err != nil {
+			return fmt.Errorf("encountered an error creating a directory to clone the dotfiles into: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+
+		// the dotfiles are cloned on the host because the image may not have git
+		dir = filepath.Join(tmp, "dotfiles")
+		fmt.Fprintln(w, "Cloning the dotfiles", dotfiles.Source)
+		out, err := exec.Command("git", "clone", "--depth", "1", dotfiles.Source, dir).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("encountered an error cloning the dotfiles: %w | out: %s", err, out)
+		}
+	} else if info, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("encountered an error reading the dotfiles directory `%s`: %w", dir, err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("the dotfiles source `%s` is not a directory or a git repository URL", dir)
+	}
+
+	container := containerutil.Container{
+		Name: workspace.ContainerName(workspaceName),
+	}
+
+	// the staging directory is created by root so only root can remove it
+	cleanup := func() error {
+		return containerUtil.Exec(containerutil.ExecOptions{User: "0"}, container.Name, "rm", "-rf", dotfilesStagingDir)
+	}
+
+	err := cleanup()
+	if err != nil {
+		return fmt.Errorf("encountered an error removing previously copied dotfiles: %w", err)
+	}
+	defer cleanup()
+
+	fmt.Fprintln(w, "Copying the dotfiles into the workspace container:", container.Name)
+	// the trailing /. copies the contents of the directory instead of the directory itself
+	out, err := containerUtil.CopyToContainer(container, containerutil.Volume{
+		HostPath:  dir + string(filepath.Separator) + ".",
+		MountPath: dotfilesStagingDir,
+	})
+	if err != nil {
+		return fmt.Errorf("encountered an error copying the dotfiles into the workspace container: %w | out: %s", err, out)
+	}
+
+	fmt.Fprintln(w, "Installing the dotfiles")
+	err = containerUtil.Exec(containerutil.ExecOptions{}, container.Name, "sh", "-c", installDotfilesScript, "sh", dotfiles.Install)
+	if err != nil {
+		return fmt.Errorf("encountered an error installing the dotfiles: %w", err)
+	}
+
+	return nil
+}
+
+// isGitSource returns whether or not the dotfiles source is the URL of a git repository
+// rather than a local directory. Existing local paths are never treated as URLs
+func isGitSource(source string) bool {
+	if _, err := os.Stat(source); err == nil {
+		return false
+	}
+
+	return strings.Contains(source, "://") || strings.HasPrefix(source, "git@") || strings.HasSuffix(source, ".git")
+}
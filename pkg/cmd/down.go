@@ -1,52 +1,254 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 
 	"github.com/everettraven/cade/pkg/containerutil"
+	"github.com/everettraven/cade/pkg/workspace"
 	"github.com/spf13/cobra"
 )
 
 var persistWorkdir bool
+var force bool
+var dryRun bool
+var trash bool
+var all bool
 
 var downCmd = &cobra.Command{
 	Use:   "down [WORKSPACE]",
 	Short: "removes a containerized development workspace",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if all {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return down(args[0], containerutil.NewContainerUtil())
+		containerUtil, err := newContainerUtil()
+		if err != nil {
+			return err
+		}
+
+		if all {
+			return downAll(containerUtil)
+		}
+		return down(args[0], containerUtil)
 	},
 }
 
 func init() {
 	downCmd.Flags().BoolVarP(&persistWorkdir, "persist-workdir", "p", false, "Persist the working directory of the workspace")
+	downCmd.Flags().BoolVarP(&force, "force", "f", false, "Remove the working directory without asking for confirmation")
+	downCmd.Flags().BoolVar(&dryRun, "dry-run", false, "List what would be removed without removing anything")
+	downCmd.Flags().BoolVarP(&trash, "trash", "t", false, "Move the working directory to the trash so it can be restored with `cade restore`")
+	downCmd.Flags().BoolVarP(&all, "all", "a", false, "Remove every cade workspace")
+}
+
+func downAll(containerUtil containerutil.ContainerUtil) error {
+	containers, err := containerUtil.ContainerList()
+	if err != nil {
+		return fmt.Errorf("encountered an error attempting to get a list of containers: %w", err)
+	}
+
+	failed := []string{}
+	for _, container := range containers {
+		workspaceName, ok := workspace.NameFromContainer(container.Name)
+		if !ok {
+			continue
+		}
+
+		fmt.Println("Removing workspace:", workspaceName)
+		err = down(workspaceName, containerUtil)
+		if err != nil {
+			fmt.Println("Failed to remove workspace", workspaceName+":", err)
+			failed = append(failed, workspaceName)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("encountered errors removing workspaces: %v", failed)
+	}
+
+	return nil
 }
 
 func down(workspaceName string, containerUtil containerutil.ContainerUtil) error {
 	container := containerutil.Container{
-		Name: fmt.Sprintf("cade-workspace-%s", workspaceName),
+		Name: workspace.ContainerName(workspaceName),
 	}
 
-	fmt.Println("Stopping the workspace container:", container.Name)
-	out, err := containerUtil.StopContainer(container)
+	workspaceDir := userSettings.WorkspaceDir(workspaceName)
+	removeWorkdir := !persistWorkdir
+	if _, err := os.Stat(workspaceDir); os.IsNotExist(err) {
+		removeWorkdir = false
+	} else if err != nil {
+		return fmt.Errorf("encountered an error checking if directory `%s` exists: %w", workspaceDir, err)
+	}
+
+	// the networks are removed after the containers using them
+	networks := []string{workspace.NetworkName(workspaceName)}
+	composeProject := ""
+	syncedWorkdir := false
+	inspected, _, err := containerUtil.InspectContainer(container.Name)
+	if err == nil {
+		composeProject = inspected.Labels[workspace.ComposeProjectLabel]
+		syncedWorkdir = inspected.Labels[workspace.SyncLabel] != ""
+		if inspected.Network != "" {
+			networks = append(networks, inspected.Network)
+		}
+	} else if !errors.Is(err, containerutil.ErrNotFound) {
+		return fmt.Errorf("encountered an error inspecting the workspace container: %w", err)
+	}
+
+	// a synced working directory gets the changes made in the workspace since the last
+	// sync before it is checked for changes that would be lost. Syncing is resumed if
+	// removing the workspace is aborted
+	syncing, err := runningSync(workspaceName)
 	if err != nil {
-		return fmt.Errorf("encountered an error stopping the workspace container: %w | out: %s", err, out)
+		return err
 	}
 
-	fmt.Println("Removing the workspace container:", container.Name)
-	out, err = containerUtil.RemoveContainer(container)
+	if syncing != nil && !dryRun {
+		err = stopSync(workspaceName)
+		if err != nil {
+			return err
+		}
+	}
+
+	var gitChanges []workspace.GitChanges
+	if removeWorkdir {
+		var err error
+		gitChanges, err = workspace.FindGitChanges(workspaceDir)
+		if err != nil {
+			fmt.Println("Unable to check the working directory for git changes:", err)
+		}
+
+		for _, changes := range gitChanges {
+			fmt.Println("WARNING: the git repository", changes.Repository, "has changes that would be lost:")
+			for _, file := range changes.Uncommitted {
+				fmt.Println("  uncommitted:", file)
+			}
+			for _, commit := range changes.Unpushed {
+				fmt.Println("  unpushed:", commit)
+			}
+		}
+	}
+
+	if dryRun {
+		services, err := serviceContainers(workspaceName, containerUtil)
+		if err != nil {
+			return err
+		}
+
+		if syncing != nil {
+			fmt.Println("Would sync the working directory a final time and stop syncing it")
+		}
+		if composeProject != "" {
+			fmt.Println("Would tear down the compose project", composeProject)
+		} else {
+			fmt.Println("Would stop and remove the workspace container:", container.Name)
+		}
+		if syncedWorkdir {
+			fmt.Println("Would remove the working directory volume:", workspace.WorkdirVolumeName(workspaceName))
+		}
+		for _, service := range services {
+			fmt.Println("Would stop and remove the service container:", service.Name)
+		}
+		if removeWorkdir && trash {
+			fmt.Println("Would move the workspace working directory to the trash:", workspaceDir)
+		} else if removeWorkdir {
+			fmt.Println("Would remove the workspace working directory:", workspaceDir)
+		}
+		return nil
+	}
+
+	// moving the working directory to the trash can be undone
+	// so confirmation is only required when permanently removing it
+	if removeWorkdir && !trash && !force {
+		question := fmt.Sprintf("Permanently remove the workspace working directory %s?", workspaceDir)
+		if len(gitChanges) > 0 {
+			question = fmt.Sprintf("Permanently remove the workspace working directory %s and lose the git changes listed above?", workspaceDir)
+		}
+
+		if !confirm(question) {
+			// the sync was stopped to check the final changes so it is resumed since nothing is removed
+			if syncing != nil {
+				fmt.Println("Resuming syncing the working directory")
+				err = startSync(os.Stdout, workspaceName, false, containerUtil)
+				if err != nil {
+					return fmt.Errorf("aborted removing workspace %q and encountered an error resuming syncing its working directory: %w", workspaceName, err)
+				}
+			}
+
+			return fmt.Errorf("aborted removing workspace %q. Use --persist-workdir to keep the working directory or --force to skip confirmation", workspaceName)
+		}
+	}
+
+	if composeProject != "" {
+		fmt.Println("Tearing down the compose project:", composeProject)
+		out, err := containerUtil.ComposeDown(composeProject)
+		if err != nil {
+			return fmt.Errorf("encountered an error tearing down the compose project: %w | out: %s", err, out)
+		}
+	} else {
+		err = removeWorkspaceContainer(container, containerUtil)
+		if err != nil {
+			return err
+		}
+	}
+
+	if syncedWorkdir {
+		volume := containerutil.ContainerVolume{Name: workspace.WorkdirVolumeName(workspaceName)}
+		fmt.Println("Removing the working directory volume:", volume.Name)
+		out, err := containerUtil.RemoveVolume(volume)
+		if err != nil && !errors.Is(err, containerutil.ErrNotFound) {
+			return fmt.Errorf("encountered an error removing the working directory volume: %w | out: %s", err, out)
+		}
+	}
+
+	err = downServices(os.Stdout, workspaceName, containerUtil)
 	if err != nil {
-		return fmt.Errorf("encountered an error removing the workspace container: %w | out: %s", err, out)
+		return err
+	}
+
+	for _, network := range networks {
+		err = removeUnusedNetwork(os.Stdout, network, containerUtil)
+		if err != nil {
+			return err
+		}
 	}
 
+	err = os.RemoveAll(workspace.RuntimeDir(workspaceName))
+	if err != nil {
+		return fmt.Errorf("encountered an error removing the workspace runtime directory: %w", err)
+	}
+
+	// the logs are kept with a persisted working directory
+	// so the workspace can be debugged when it is brought up again
 	if !persistWorkdir {
-		home, err := os.UserHomeDir()
+		err = os.RemoveAll(workspace.LogsDir(userSettings.WorkspaceRoot, workspaceName))
+		if err != nil {
+			return fmt.Errorf("encountered an error removing the workspace logs: %w", err)
+		}
+	}
+
+	if removeWorkdir && trash {
+		trashPath, err := workspace.Trash(userSettings.WorkspaceRoot, workspaceName)
 		if err != nil {
-			return fmt.Errorf("encountered an error getting the user home directory: %w", err)
+			return err
 		}
+		fmt.Println("Moved the workspace working directory to the trash:", trashPath)
 
-		workspaceDir := filepath.Join(home, "cade", "workspaces", workspaceName)
+		purged, err := workspace.PurgeTrash(userSettings.WorkspaceRoot, userSettings.TrashRetention)
+		if err != nil {
+			return fmt.Errorf("encountered an error purging expired entries from the trash: %w", err)
+		}
+		for _, entry := range purged {
+			fmt.Println("Purged expired working directory from the trash:", entry.Path)
+		}
+	} else if removeWorkdir {
 		fmt.Println("Cleaning up the workspace working directory:", workspaceDir)
 		err = os.RemoveAll(workspaceDir)
 		if err != nil {
@@ -56,3 +258,26 @@ func down(workspaceName string, containerUtil containerutil.ContainerUtil) error
 
 	return nil
 }
+
+// removeWorkspaceContainer stops and removes the workspace container. The working directory
+// should still be cleaned up if the container is already stopped or has been removed manually
+// so those are not errors
+func removeWorkspaceContainer(container containerutil.Container, containerUtil containerutil.ContainerUtil) error {
+	fmt.Println("Stopping the workspace container:", container.Name)
+	out, err := containerUtil.StopContainer(container)
+	if errors.Is(err, containerutil.ErrNotFound) || errors.Is(err, containerutil.ErrNotRunning) {
+		fmt.Println("The workspace container is already stopped")
+	} else if err != nil {
+		return fmt.Errorf("encountered an error stopping the workspace container: %w | out: %s", err, out)
+	}
+
+	fmt.Println("Removing the workspace container:", container.Name)
+	out, err = containerUtil.RemoveContainer(container)
+	if errors.Is(err, containerutil.ErrNotFound) {
+		fmt.Println("The workspace container does not exist")
+	} else if err != nil {
+		return fmt.Errorf("encountered an error removing the workspace container: %w | out: %s", err, out)
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/everettraven/cade/pkg/containerutil"
+	"github.com/everettraven/cade/pkg/workspace"
+	"github.com/spf13/cobra"
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec [WORKSPACE] -- [COMMAND] [ARGS...]",
+	Short: "runs a command in the workspace specified",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		containerUtil, err := newContainerUtil()
+		if err != nil {
+			return err
+		}
+		return execCommand(args[0], args[1:], containerUtil)
+	},
+}
+
+func execCommand(workspaceName string, command []string, containerUtil containerutil.ContainerUtil) error {
+	execOpts := containerutil.ExecOptions{
+		Interactive: true,
+	}
+
+	stop, err := startSession(workspaceName, &execOpts, containerUtil)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	containerName := workspace.ContainerName(workspaceName)
+
+	err = containerUtil.Exec(execOpts, containerName, command...)
+	if err != nil {
+		return fmt.Errorf("encountered an error running the command in the workspace: %w", err)
+	}
+
+	return nil
+}
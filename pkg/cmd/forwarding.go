@@ -0,0 +1,359 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/everettraven/cade/pkg/config"
+	"github.com/everettraven/cade/pkg/containerutil"
+	"github.com/everettraven/cade/pkg/workspace"
+)
+
+const (
+	// containerSSHAuthSock is where the host SSH agent socket is mounted in workspace containers
+	containerSSHAuthSock = "/run/cade/ssh-agent.sock"
+	// dockerDesktopSSHAuthSock is the SSH agent socket Docker Desktop forwards from macOS hosts.
+	// The host SSH_AUTH_SOCK can't be mounted directly because it is not in the Docker VM
+	dockerDesktopSSHAuthSock = "/run/host-services/ssh-auth.sock"
+	// containerGitConfig is where the host git config is mounted in workspace containers
+	containerGitConfig = "/etc/gitconfig"
+	// containerRuntimeDir is where the runtime directory of the workspace is mounted in workspace containers
+	containerRuntimeDir = "/run/cade/host"
+
+	gitCredentialSocket = "git-credential.sock"
+	gitCredentialHelper = "git-credential-cade"
+)
+
+// gitCredentialHelperScript is the git credential helper in workspace containers. It forwards
+// the requests of git to the credential proxy on the host through its socket
+const gitCredentialHelperScript = `#!/bin/sh
+socket="$(dirname "$0")/` + gitCredentialSocket + `"
+if [ ! -S "$socket" ]; then
+	echo "cade: git credentials are only forwarded while a cade term or exec session is attached" >&2
+	exit 0
+fi
+
+# the request ends with an empty line. It is added when sending because
+# command substitution removes trailing new lines
+request=$(printf '%s\n' "$1"; cat)
+if command -v socat >/dev/null 2>&1; then
+	printf '%s\n\n' "$request" | socat - "UNIX-CONNECT:$socket"
+elif command -v python3 >/dev/null 2>&1; then
+	printf '%s\n\n' "$request" | python3 -c '
+import socket, sys
+s = socket.socket(socket.AF_UNIX)
+s.connect(sys.argv[1])
+s.sendall(sys.stdin.buffer.read())
+while True:
+    data = s.recv(4096)
+    if not data:
+        break
+    sys.stdout.buffer.write(data)
+' "$socket"
+elif nc -h 2>&1 | grep -q -- '-U'; then
+	printf '%s\n\n' "$request" | nc -U "$socket"
+else
+	echo "cade: forwarding git credentials requires socat, python3 or nc with unix socket support in the workspace" >&2
+fi
+`
+
+// forwarding returns the volumes, environment variables and labels that forward
+// the SSH agent, git config and git credentials of the host as configured
+func forwarding(w io.Writer, workspaceName string, workspaceConfig *config.WorkspaceConfig) ([]containerutil.Volume, map[string]string, map[string]string, error) {
+	volumes := []containerutil.Volume{}
+	labels := map[string]string{}
+
+	if workspaceConfig.ForwardSSHAgent {
+		socket, err := sshAgentSocket()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		hostPath := socket
+		if runtime.GOOS == "darwin" {
+			hostPath = dockerDesktopSSHAuthSock
+		}
+
+		fmt.Fprintln(w, "Forwarding the SSH agent", socket)
+		volumes = append(volumes, containerutil.Volume{HostPath: hostPath, MountPath: containerSSHAuthSock})
+		labels[workspace.SSHAgentLabel] = socket
+	}
+
+	if workspaceConfig.ForwardGitConfig {
+		gitConfig, err := hostGitConfig()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if gitConfig == "" {
+			fmt.Fprintln(w, "Not forwarding the git config because neither ~/.gitconfig nor ~/.config/git/config exist")
+		} else {
+			fmt.Fprintln(w, "Forwarding the git config", gitConfig)
+			volumes = append(volumes, containerutil.Volume{HostPath: gitConfig, MountPath: containerGitConfig, ReadOnly: true})
+		}
+	}
+
+	if workspaceConfig.ForwardGitCredentials {
+		runtimeDir, err := workspace.EnsureRuntimeDir(workspaceName)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		err = os.WriteFile(filepath.Join(runtimeDir, gitCredentialHelper), []byte(gitCredentialHelperScript), 0755)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("encountered an error writing the git credential helper: %w", err)
+		}
+
+		fmt.Fprintln(w, "Forwarding git credentials while a term or exec session is attached")
+		volumes = append(volumes, containerutil.Volume{HostPath: runtimeDir, MountPath: containerRuntimeDir})
+		labels[workspace.GitCredentialsLabel] = "true"
+		if workspaceConfig.ForwardGitCredentialChanges {
+			labels[workspace.GitCredentialChangesLabel] = "true"
+		}
+	}
+
+	return volumes, forwardingEnv(labels), labels, nil
+}
+
+// forwardingEnv returns the environment variables of a workspace container
+// with the provided labels that point to what is forwarded into it
+func forwardingEnv(labels map[string]string) map[string]string {
+	env := map[string]string{}
+
+	if labels[workspace.SSHAgentLabel] != "" {
+		env["SSH_AUTH_SOCK"] = containerSSHAuthSock
+	}
+
+	if labels[workspace.GitCredentialsLabel] == "true" {
+		// configures the helper without changing any git config files in the container
+		env["GIT_CONFIG_COUNT"] = "1"
+		env["GIT_CONFIG_KEY_0"] = "credential.helper"
+		env["GIT_CONFIG_VALUE_0"] = containerRuntimeDir + "/" + gitCredentialHelper
+	}
+
+	return env
+}
+
+// sshAgentSocket returns the path of the host SSH agent socket
+// or an error describing why it can't be forwarded
+func sshAgentSocket() (string, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return "", fmt.Errorf("forward_ssh_agent is set but SSH_AUTH_SOCK is not. Start an SSH agent with `eval $(ssh-agent)` and add your keys with `ssh-add`")
+	}
+
+	info, err := os.Stat(socket)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("forward_ssh_agent is set but the SSH agent socket %s from SSH_AUTH_SOCK does not exist. The agent may have stopped, start it again with `eval $(ssh-agent)`", socket)
+	} else if err != nil {
+		return "", fmt.Errorf("encountered an error checking the SSH agent socket %s: %w", socket, err)
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return "", fmt.Errorf("forward_ssh_agent is set but SSH_AUTH_SOCK is set to %s which is not a socket", socket)
+	}
+
+	return socket, nil
+}
+
+// hostGitConfig returns the path of the git config of the host user. Returns an empty path if there is none
+func hostGitConfig() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("encountered an error getting the user home directory: %w", err)
+	}
+
+	xdgConfig := os.Getenv("XDG_CONFIG_HOME")
+	if xdgConfig == "" {
+		xdgConfig = filepath.Join(home, ".config")
+	}
+
+	for _, path := range []string{filepath.Join(home, ".gitconfig"), filepath.Join(xdgConfig, "git", "config")} {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !os.IsNotExist(err) {
+			return "", fmt.Errorf("encountered an error checking if the git config %s exists: %w", path, err)
+		}
+	}
+
+	return "", nil
+}
+
+// startSession configures the exec options of a term or exec session in the workspace
+// and starts what the workspace forwards for the duration of the session. The
+// returned function stops it and must be called when the session ends
+func startSession(workspaceName string, execOpts *containerutil.ExecOptions, containerUtil containerutil.ContainerUtil) (func(), error) {
+	container, _, err := containerUtil.InspectContainer(workspace.ContainerName(workspaceName))
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error getting the workspace container: %w", err)
+	}
+
+	if socket := container.Labels[workspace.SSHAgentLabel]; socket != "" {
+		execOpts.Env = map[string]string{"SSH_AUTH_SOCK": containerSSHAuthSock}
+
+		if _, err := os.Stat(socket); err != nil {
+			fmt.Fprintln(os.Stderr, "WARNING: the SSH agent socket", socket, "forwarded into the workspace no longer exists. Recreate the workspace with `cade up` to forward the current agent")
+		}
+	}
+
+	if container.Labels[workspace.SyncLabel] != "" {
+		if status, err := runningSync(workspaceName); err == nil && status == nil {
+			fmt.Fprintln(os.Stderr, "WARNING: the working directory of the workspace is not being synced with the host. Start syncing it with `cade sync start", workspaceName+"`")
+		}
+	}
+
+	if container.Labels[workspace.GitCredentialsLabel] == "true" {
+		owner, err := gitCredentialSocketOwner(container.Name, execOpts.User, containerUtil)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "WARNING: git credentials are not forwarded:", err)
+			return func() {}, nil
+		}
+
+		return startGitCredentialProxy(workspaceName, owner, container.Labels[workspace.GitCredentialChangesLabel] == "true")
+	}
+
+	return func() {}, nil
+}
+
+// gitCredentialSocketOwner returns the UID the git credential socket is owned by so the user of the
+// session can connect to it, or -1 if it is the current user. Other runtimes than Linux run containers
+// in a VM that maps the owner of mounted files so the socket is owned by the current user there too.
+// Returns an error if the socket can't be owned by the user of the session
+func gitCredentialSocketOwner(containerName string, user string, containerUtil containerutil.ContainerUtil) (int, error) {
+	if runtime.GOOS != "linux" {
+		return -1, nil
+	}
+
+	out := &bytes.Buffer{}
+	err := containerUtil.Exec(containerutil.ExecOptions{User: user, Stdout: out}, containerName, "id", "-u")
+	if err != nil {
+		return 0, fmt.Errorf("encountered an error getting the UID of the workspace user: %w", err)
+	}
+
+	uid, err := strconv.Atoi(strings.TrimSpace(out.String()))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected UID %q of the workspace user", strings.TrimSpace(out.String()))
+	}
+
+	// root in the container can access the files of any user
+	if uid == 0 || uid == os.Getuid() {
+		return -1, nil
+	}
+
+	// only root can give files to other users
+	if os.Getuid() != 0 {
+		return 0, fmt.Errorf("the workspace user %d is not the host user. Recreate the workspace with user_mapping: auto to forward them", uid)
+	}
+
+	return uid, nil
+}
+
+// startGitCredentialProxy listens on the socket the git credential helper of the workspace connects
+// to and answers its requests with the git credential helpers of the host. The socket and the runtime
+// directory it is in are given to the owner unless it is -1. Requests to store or erase credentials
+// are only answered if changes are allowed
+func startGitCredentialProxy(workspaceName string, owner int, allowChanges bool) (func(), error) {
+	runtimeDir, err := workspace.EnsureRuntimeDir(workspaceName)
+	if err != nil {
+		return nil, err
+	}
+	socket := filepath.Join(runtimeDir, gitCredentialSocket)
+
+	// a socket left behind by a session that didn't stop cleanly can't be listened on
+	err = os.Remove(socket)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("encountered an error removing the old git credential socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error starting the git credential proxy: %w", err)
+	}
+
+	err = os.Chmod(socket, 0600)
+	if err == nil && owner != -1 {
+		err = os.Chown(socket, owner, -1)
+		if err == nil {
+			err = os.Chown(runtimeDir, owner, -1)
+		}
+	}
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("encountered an error setting the permissions of the git credential socket: %w", err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if errors.Is(err, net.ErrClosed) {
+				return
+			} else if err != nil {
+				continue
+			}
+
+			go serveGitCredential(conn, allowChanges)
+		}
+	}()
+
+	return func() {
+		listener.Close()
+	}, nil
+}
+
+// gitCredentialActions maps the actions of credential helpers to the git credential subcommands
+var gitCredentialActions = map[string]string{
+	"get":   "fill",
+	"store": "approve",
+	"erase": "reject",
+}
+
+// serveGitCredential answers a credential helper request. A request is the action on
+// the first line followed by the credential attributes and ends with an empty line.
+// Only get requests are answered unless changes are allowed
+func serveGitCredential(conn net.Conn, allowChanges bool) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	action, err := reader.ReadString('\n')
+	if err != nil {
+		return
+	}
+
+	subcommand, ok := gitCredentialActions[strings.TrimSpace(action)]
+	if !ok || (subcommand != "fill" && !allowChanges) {
+		return
+	}
+
+	attributes := &bytes.Buffer{}
+	for {
+		line, err := reader.ReadString('\n')
+		if strings.TrimSpace(line) == "" || err != nil {
+			break
+		}
+		attributes.WriteString(line)
+	}
+
+	cmd := exec.Command("git", "credential", subcommand)
+	cmd.Stdin = attributes
+	// git must not prompt on the host terminal the session is attached to
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	out, err := cmd.Output()
+	if err != nil {
+		return
+	}
+
+	// only a get request has a response
+	if subcommand == "fill" {
+		conn.Write(out)
+	}
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/everettraven/cade/pkg/scaffold"
+	"github.com/spf13/cobra"
+)
+
+var yes bool
+var templateDirs []string
+var overwrite bool
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "scaffolds a workspace config and Containerfile for the project in the current directory",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return initWorkspace(".")
+	},
+}
+
+func init() {
+	initCmd.Flags().BoolVarP(&yes, "yes", "y", false, "accept the proposed values without prompting")
+	initCmd.Flags().StringArrayVarP(&templateDirs, "template-dir", "t", nil, "a directory of templates to render in addition to the built-in ones. Can be repeated")
+	initCmd.Flags().BoolVarP(&overwrite, "force", "f", false, "overwrite existing files")
+}
+
+func initWorkspace(dir string) error {
+	project, err := scaffold.Detect(dir)
+	if err != nil {
+		return fmt.Errorf("encountered an error inspecting the project: %w", err)
+	}
+
+	for _, toolchain := range project.Toolchains {
+		fmt.Println("Detected", toolchain.Name, "from", toolchain.File, "- proposed base image:", toolchain.BaseImage)
+	}
+
+	if !yes {
+		project.Name = ask("Workspace name", project.Name)
+		project.BaseImage = ask("Base image", project.BaseImage)
+	}
+
+	files, err := scaffold.Render(project, templateDirs...)
+	if err != nil {
+		return fmt.Errorf("encountered an error rendering the templates: %w", err)
+	}
+
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// nothing is written unless every file can be
+	if !overwrite {
+		for _, name := range names {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return fmt.Errorf("the file `%s` already exists. Use --force to overwrite it", name)
+			}
+		}
+	}
+
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		err = os.MkdirAll(filepath.Dir(path), 0777)
+		if err != nil {
+			return fmt.Errorf("encountered an error creating the directory for `%s`: %w", name, err)
+		}
+
+		err = os.WriteFile(path, files[name], 0644)
+		if err != nil {
+			return fmt.Errorf("encountered an error writing `%s`: %w", name, err)
+		}
+
+		fmt.Println("Wrote", path)
+	}
+
+	fmt.Println("Workspace scaffolded! Create it with: cade up cadeconfig.yaml")
+	return nil
+}
@@ -2,9 +2,11 @@ package cmd
 
 import (
 	"fmt"
-	"strings"
+	"io"
+	"os"
 
 	"github.com/everettraven/cade/pkg/containerutil"
+	"github.com/everettraven/cade/pkg/workspace"
 	"github.com/spf13/cobra"
 )
 
@@ -12,20 +14,44 @@ var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "list the current workspaces",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return list(containerutil.NewContainerUtil())
+		containerUtil, err := newContainerUtil()
+		if err != nil {
+			return err
+		}
+		return list(os.Stdout, containerUtil)
 	},
 }
 
-func list(containerUtil containerutil.ContainerUtil) error {
+func list(w io.Writer, containerUtil containerutil.ContainerUtil) error {
 	containers, err := containerUtil.ContainerList()
 	if err != nil {
 		return fmt.Errorf("encountered an error attempting to get a list of containers: %w", err)
 	}
 
-	fmt.Println("Available workspaces:")
+	// services are grouped under the workspace they belong to
+	services := map[string][]string{}
+	for _, container := range containers {
+		if service := container.Labels[workspace.ServiceLabel]; service != "" {
+			workspaceName := container.Labels[workspace.WorkspaceLabel]
+			services[workspaceName] = append(services[workspaceName], fmt.Sprintf("%s (%s)", service, container.State))
+		}
+	}
+
+	fmt.Fprintln(w, "Available workspaces:")
 	for _, container := range containers {
-		if strings.Contains(container.Name, "cade-workspace") {
-			fmt.Println("-", strings.Replace(container.Name, "cade-workspace-", "", -1))
+		workspaceName, ok := workspace.NameFromContainer(container.Name)
+		if !ok {
+			continue
+		}
+
+		if workspaceProfile := container.Labels[workspace.ProfileLabel]; workspaceProfile != "" {
+			fmt.Fprintln(w, "-", workspaceName, "(profile:", workspaceProfile+")")
+		} else {
+			fmt.Fprintln(w, "-", workspaceName)
+		}
+
+		for _, service := range services[workspaceName] {
+			fmt.Fprintln(w, "    service:", service)
 		}
 	}
 
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/everettraven/cade/pkg/containerutil"
+	"github.com/everettraven/cade/pkg/workspace"
+	"github.com/spf13/cobra"
+)
+
+var follow bool
+var since string
+var tail string
+var upLog bool
+
+var logsCmd = &cobra.Command{
+	Use:   "logs [WORKSPACE]",
+	Short: "prints the output of the workspace container specified, or of the last `cade up` of the workspace with --up",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if upLog {
+			return printUpLog(args[0])
+		}
+
+		containerUtil, err := newContainerUtil()
+		if err != nil {
+			return err
+		}
+		return logs(args[0], containerUtil)
+	},
+}
+
+func init() {
+	logsCmd.Flags().BoolVarP(&follow, "follow", "f", false, "keep printing new output until the workspace container stops")
+	logsCmd.Flags().StringVar(&since, "since", "", "only print output since a timestamp, e.g. 2023-06-01T15:04:05, or a relative time, e.g. 10m")
+	logsCmd.Flags().StringVar(&tail, "tail", "", "the number of lines to print from the end of the output")
+	logsCmd.Flags().BoolVar(&upLog, "up", false, "print the output of the last `cade up` of the workspace instead")
+}
+
+func logs(workspaceName string, containerUtil containerutil.ContainerUtil) error {
+	err := containerUtil.Logs(containerutil.LogsOptions{
+		Follow: follow,
+		Since:  since,
+		Tail:   tail,
+	}, workspace.ContainerName(workspaceName))
+	if err != nil {
+		return fmt.Errorf("encountered an error getting the logs of the workspace container: %w", err)
+	}
+
+	return nil
+}
+
+// printUpLog prints the log of the last `cade up` of the workspace
+func printUpLog(workspaceName string) error {
+	if follow || since != "" {
+		return fmt.Errorf("--follow and --since can't be used with --up")
+	}
+
+	upLogs, err := workspace.ListUpLogs(userSettings.WorkspaceRoot, workspaceName)
+	if err != nil {
+		return err
+	}
+
+	if len(upLogs) == 0 {
+		return fmt.Errorf("no logs of `cade up` found for workspace %q in %s", workspaceName, workspace.LogsDir(userSettings.WorkspaceRoot, workspaceName))
+	}
+
+	out, err := os.ReadFile(upLogs[0])
+	if err != nil {
+		return fmt.Errorf("encountered an error reading the log `%s`: %w", upLogs[0], err)
+	}
+
+	if tail != "" && tail != "all" {
+		lines, err := strconv.Atoi(tail)
+		if err != nil || lines < 0 {
+			return fmt.Errorf("--tail must be a number of lines or all, got %q", tail)
+		}
+		out = lastLines(out, lines)
+	}
+
+	os.Stdout.Write(out)
+	return nil
+}
+
+// lastLines returns the last n lines of the output
+func lastLines(out []byte, n int) []byte {
+	lines := bytes.SplitAfter(out, []byte("\n"))
+	if len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+
+	if n < len(lines) {
+		lines = lines[len(lines)-n:]
+	}
+
+	return bytes.Join(lines, nil)
+}
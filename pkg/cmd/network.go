@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/everettraven/cade/pkg/config"
+	"github.com/everettraven/cade/pkg/containerutil"
+	"github.com/everettraven/cade/pkg/workspace"
+)
+
+// ensureNetwork makes sure the network exists, creating it with the provided
+// labels if it is missing and the config allows it to be created
+func ensureNetwork(w io.Writer, network config.NetworkConfig, labels map[string]string, containerUtil containerutil.ContainerUtil) error {
+	_, err := containerUtil.InspectNetwork(network.Name)
+	if err == nil {
+		return nil
+	} else if !errors.Is(err, containerutil.ErrNotFound) {
+		return fmt.Errorf("encountered an error checking if the network %s exists: %w", network.Name, err)
+	}
+
+	if !network.CreateIfMissing {
+		return fmt.Errorf("the network %s does not exist. Create it or set create_if_missing in the network config", network.Name)
+	}
+
+	fmt.Fprintln(w, "Creating the network:", network.Name)
+	out, err := containerUtil.CreateNetwork(containerutil.Network{
+		Name:   network.Name,
+		Driver: network.Driver,
+		Labels: labels,
+	})
+	if err != nil && !errors.Is(err, containerutil.ErrAlreadyExists) {
+		return fmt.Errorf("encountered an error creating the network %s: %w | out: %s", network.Name, err, out)
+	}
+
+	return nil
+}
+
+// removeUnusedNetwork removes the network if cade created it and no containers, including
+// stopped ones that would fail to start without it, are using it anymore
+func removeUnusedNetwork(w io.Writer, name string, containerUtil containerutil.ContainerUtil) error {
+	network, err := containerUtil.InspectNetwork(name)
+	if errors.Is(err, containerutil.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("encountered an error inspecting the network %s: %w", name, err)
+	}
+
+	if network.Labels[workspace.ManagedLabel] != "true" {
+		return nil
+	}
+
+	// the network only lists the containers that are running
+	containers, err := containerUtil.ContainerList()
+	if err != nil {
+		return fmt.Errorf("encountered an error getting the containers using the network %s: %w", name, err)
+	}
+
+	users := network.Containers
+	running := map[string]bool{}
+	for _, user := range users {
+		running[user] = true
+	}
+
+	for _, container := range containers {
+		for _, containerNetwork := range container.Networks {
+			if containerNetwork == name && !running[container.Name] {
+				users = append(users, container.Name)
+			}
+		}
+	}
+
+	if len(users) > 0 {
+		fmt.Fprintln(w, "Keeping the network", name, "because it is still used by:", users)
+		return nil
+	}
+
+	fmt.Fprintln(w, "Removing the network:", name)
+	out, err := containerUtil.RemoveNetwork(name)
+	if err != nil && !errors.Is(err, containerutil.ErrNotFound) {
+		return fmt.Errorf("encountered an error removing the network %s: %w | out: %s", name, err, out)
+	}
+
+	return nil
+}
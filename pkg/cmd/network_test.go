@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/everettraven/cade/pkg/containerutil"
+	"github.com/everettraven/cade/pkg/workspace"
+)
+
+// fakeContainerUtil is a container runtime with fixed containers and networks.
+// Calling a method it doesn't implement panics through the nil embedded interface
+type fakeContainerUtil struct {
+	containerutil.ContainerUtil
+
+	containers      []containerutil.Container
+	networks        map[string]*containerutil.Network
+	removedNetworks []string
+}
+
+func (f *fakeContainerUtil) ContainerList() ([]containerutil.Container, error) {
+	return f.containers, nil
+}
+
+func (f *fakeContainerUtil) InspectNetwork(name string) (*containerutil.Network, error) {
+	network, ok := f.networks[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: network %q", containerutil.ErrNotFound, name)
+	}
+
+	return network, nil
+}
+
+func (f *fakeContainerUtil) RemoveNetwork(name string) ([]byte, error) {
+	if _, ok := f.networks[name]; !ok {
+		return nil, fmt.Errorf("%w: network %q", containerutil.ErrNotFound, name)
+	}
+
+	delete(f.networks, name)
+	f.removedNetworks = append(f.removedNetworks, name)
+	return nil, nil
+}
+
+func TestRemoveUnusedNetwork(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		network     containerutil.Network
+		containers  []containerutil.Container
+		wantRemoved bool
+	}{
+		{
+			name:        "unused managed network",
+			network:     containerutil.Network{Name: "net", Labels: workspace.ManagedLabels()},
+			wantRemoved: true,
+		},
+		{
+			name:    "network used by a running container",
+			network: containerutil.Network{Name: "net", Labels: workspace.ManagedLabels(), Containers: []string{"other"}},
+			containers: []containerutil.Container{
+				{Name: "other", State: "running", Networks: []string{"net"}},
+			},
+		},
+		{
+			name:    "network used by a stopped container",
+			network: containerutil.Network{Name: "net", Labels: workspace.ManagedLabels()},
+			containers: []containerutil.Container{
+				{Name: "other", State: "exited", Networks: []string{"bridge", "net"}},
+			},
+		},
+		{
+			name:    "network cade didn't create",
+			network: containerutil.Network{Name: "net", Labels: map[string]string{workspace.WorkspaceLabel: "ws"}},
+		},
+		{
+			name:    "network without labels",
+			network: containerutil.Network{Name: "net"},
+		},
+		{
+			name:    "managed label that isn't true",
+			network: containerutil.Network{Name: "net", Labels: map[string]string{workspace.ManagedLabel: "false"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			network := tc.network
+			fake := &fakeContainerUtil{
+				containers: tc.containers,
+				networks:   map[string]*containerutil.Network{network.Name: &network},
+			}
+
+			err := removeUnusedNetwork(io.Discard, network.Name, fake)
+			if err != nil {
+				t.Fatalf("unexpected error removing the network: %v", err)
+			}
+
+			removed := len(fake.removedNetworks) > 0
+			if removed != tc.wantRemoved {
+				t.Errorf("expected the network to be removed to be %t, got %t", tc.wantRemoved, removed)
+			}
+		})
+	}
+}
+
+func TestRemoveUnusedNetworkMissing(t *testing.T) {
+	fake := &fakeContainerUtil{networks: map[string]*containerutil.Network{}}
+
+	err := removeUnusedNetwork(io.Discard, "net", fake)
+	if err != nil {
+		t.Errorf("expected a missing network not to be an error: %v", err)
+	}
+}
+
+func TestListGroupsServices(t *testing.T) {
+	serviceLabels := func(workspaceName string, service string) map[string]string {
+		labels := workspace.Labels(workspaceName)
+		labels[workspace.ServiceLabel] = service
+		return labels
+	}
+
+	fake := &fakeContainerUtil{containers: []containerutil.Container{
+		{Name: workspace.ServiceContainerName("a", "db"), State: "running", Labels: serviceLabels("a", "db")},
+		{Name: workspace.ContainerName("a"), State: "running", Labels: workspace.Labels("a")},
+		{Name: workspace.ContainerName("b"), State: "running", Labels: workspace.Labels("b")},
+		{Name: workspace.ServiceContainerName("b", "cache"), State: "exited", Labels: serviceLabels("b", "cache")},
+		{Name: workspace.ServiceContainerName("a", "queue"), State: "running", Labels: serviceLabels("a", "queue")},
+		{Name: "unrelated", State: "running"},
+	}}
+
+	var out bytes.Buffer
+	err := list(&out, fake)
+	if err != nil {
+		t.Fatalf("unexpected error listing the workspaces: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"Available workspaces:",
+		"- a",
+		"    service: db (running)",
+		"    service: queue (running)",
+		"- b",
+		"    service: cache (exited)",
+		"",
+	}, "\n")
+	if out.String() != want {
+		t.Errorf("expected the output:\n%s\ngot:\n%s", want, out.String())
+	}
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/everettraven/cade/pkg/containerutil"
+	"github.com/everettraven/cade/pkg/registry"
+	"github.com/everettraven/cade/pkg/workspace"
+	"github.com/spf13/cobra"
+)
+
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "lists workspaces whose image has changed since the workspace was created",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		containerUtil, err := newContainerUtil()
+		if err != nil {
+			return err
+		}
+		return outdated(containerUtil)
+	},
+}
+
+// imageStatus describes how the image of a workspace compares to the image it was created from
+type imageStatus struct {
+	ref            string
+	recorded       string
+	local          string
+	remote         string
+	pinned         bool
+	upToDate       bool
+	updateRegistry bool
+}
+
+func outdated(containerUtil containerutil.ContainerUtil) error {
+	containers, err := containerUtil.ContainerList()
+	if err != nil {
+		return fmt.Errorf("encountered an error attempting to get a list of containers: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "WORKSPACE\tIMAGE\tRECORDED\tLOCAL\tREGISTRY\tSTATUS")
+	for _, container := range containers {
+		workspaceName, ok := workspace.NameFromContainer(container.Name)
+		if !ok {
+			continue
+		}
+
+		status := workspaceImageStatus(container, containerUtil)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", workspaceName, status.ref, shortDigest(status.recorded), shortDigest(status.local), shortDigest(status.remote), status)
+	}
+
+	return w.Flush()
+}
+
+// workspaceImageStatus compares the digest recorded on the workspace container to the
+// current digest of its image locally and in the registry. Images cade built are not looked
+// up in the registry since they were never pushed and a registry image with the same name
+// is not the same image. Failures to resolve a digest are not errors
+func workspaceImageStatus(container containerutil.Container, containerUtil containerutil.ContainerUtil) *imageStatus {
+	status := &imageStatus{
+		ref:      container.Labels[workspace.ImageLabel],
+		recorded: container.Labels[workspace.ImageDigestLabel],
+	}
+
+	if status.ref == "" {
+		status.ref = container.Image
+	}
+
+	image, err := containerUtil.InspectImage(status.ref)
+	if err == nil {
+		status.local = image.Digest
+		if status.local == "" {
+			status.local = image.Id
+		}
+	}
+
+	built := strings.HasPrefix(status.ref, userSettings.ImageNamespace+"/") ||
+		(image != nil && image.Digest == "" && image.Labels[workspace.ManagedLabel] == "true")
+
+	if ref, err := registry.ParseReference(status.ref); err == nil {
+		status.pinned = ref.Digest != ""
+
+		if !status.pinned && !built {
+			credentials, err := registry.LoadCredentials(ref.Registry)
+			if err == nil {
+				status.remote, _ = registry.NewClient().Digest(ref, credentials)
+			}
+		}
+	}
+
+	status.upToDate = status.recorded != "" && status.local == status.recorded && (status.remote == "" || status.remote == status.recorded)
+	status.updateRegistry = status.remote != "" && status.remote != status.recorded
+
+	return status
+}
+
+func (s *imageStatus) String() string {
+	switch {
+	case s.pinned:
+		return "pinned"
+	case s.recorded == "":
+		return "unknown"
+	case s.upToDate:
+		return "up to date"
+	case s.updateRegistry:
+		return "update available"
+	default:
+		return "update available locally"
+	}
+}
+
+// shortDigest shortens a digest for display
+func shortDigest(digest string) string {
+	if digest == "" {
+		return "-"
+	}
+
+	const length = len("sha256:") + 12
+	if len(digest) > length {
+		return digest[:length]
+	}
+
+	return digest
+}
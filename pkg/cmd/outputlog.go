@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// upOutput is the log of the running `cade up`. Output that is too verbose for the
+// terminal, such as the output of successful image builds, is only written to it
+var upOutput io.Writer = io.Discard
+
+// outputLog is the log file of a command. Output written before the
+// log file is opened is kept and written to it when it is
+type outputLog struct {
+	mu     sync.Mutex
+	file   *os.File
+	buffer bytes.Buffer
+}
+
+// Open creates the log file at the provided path and writes the output logged so far to it.
+// Returns an error if any occur during the process
+func (l *outputLog) Open(path string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0777)
+	if err != nil {
+		return fmt.Errorf("encountered an error ensuring the directory `%s` exists: %w", filepath.Dir(path), err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("encountered an error creating the log file `%s`: %w", path, err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.file = file
+	l.file.Write(l.buffer.Bytes())
+	l.buffer.Reset()
+
+	return nil
+}
+
+// Write writes to the log only. Failing to write to the log file is not an
+// error because it would stop the output from being written to the terminal
+func (l *outputLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file != nil {
+		l.file.Write(p)
+	} else {
+		l.buffer.Write(p)
+	}
+
+	return len(p), nil
+}
+
+// Close closes the log file. The error the command failed
+// with, if any, is written to the log since it is printed after
+func (l *outputLog) Close(cmdErr error) {
+	if cmdErr != nil {
+		fmt.Fprintln(l, "Error:", cmdErr)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+}
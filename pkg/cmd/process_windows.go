@@ -0,0 +1,41 @@
+//go:build windows
+
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// createNewProcessGroup is the CREATE_NEW_PROCESS_GROUP process creation flag
+const createNewProcessGroup = 0x00000200
+
+// detachProcess makes the command run in its own process group so it
+// keeps running when the console it was started from is closed
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: createNewProcessGroup}
+}
+
+// processRunning returns whether or not the process with the provided ID is running
+func processRunning(pid int) bool {
+	// finding a process on Windows opens it, which fails once it has exited
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+
+	return true
+}
+
+// terminateProcess stops the process with the provided ID. Windows processes
+// can't be asked to exit so it doesn't get to finish what it is doing
+func terminateProcess(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	return process.Kill()
+}
@@ -0,0 +1,290 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/everettraven/cade/pkg/containerutil"
+	"github.com/everettraven/cade/pkg/workspace"
+	"github.com/spf13/cobra"
+)
+
+var pruneForce bool
+var olderThan time.Duration
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "removes cade images, containers, volumes and directories that are not used by any workspace",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		containerUtil, err := newContainerUtil()
+		if err != nil {
+			return err
+		}
+		return prune(containerUtil)
+	},
+}
+
+func init() {
+	pruneCmd.Flags().BoolVarP(&pruneForce, "force", "f", false, "Remove the resources without asking for confirmation")
+	pruneCmd.Flags().DurationVar(&olderThan, "older-than", 0, "Only remove resources older than this duration, e.g. 72h")
+}
+
+// pruneCandidate is a resource that can be removed by `cade prune`
+type pruneCandidate struct {
+	kind    string
+	name    string
+	size    string
+	created time.Time
+	remove  func() error
+}
+
+func prune(containerUtil containerutil.ContainerUtil) error {
+	candidates := []pruneCandidate{}
+
+	containers, err := containerUtil.ContainerList()
+	if err != nil {
+		return fmt.Errorf("encountered an error attempting to get a list of containers: %w", err)
+	}
+
+	liveWorkspaces := map[string]bool{}
+	usedVolumes := map[string]bool{}
+	for _, container := range containers {
+		if workspaceName, ok := workspace.NameFromContainer(container.Name); ok {
+			liveWorkspaces[workspaceName] = true
+		}
+
+		for _, mount := range container.Mounts {
+			usedVolumes[mount] = true
+		}
+
+		if workspace.IsCopier(container.Name) {
+			container := container
+			candidates = append(candidates, pruneCandidate{
+				kind:    "container",
+				name:    container.Name,
+				size:    "-",
+				created: container.CreatedAt,
+				remove: func() error {
+					out, err := containerUtil.RemoveContainer(container)
+					if err != nil {
+						return fmt.Errorf("%w | out: %s", err, out)
+					}
+					return nil
+				},
+			})
+		}
+	}
+
+	dirs, err := workspace.ListDirs(userSettings.WorkspaceRoot)
+	if err != nil {
+		return err
+	}
+
+	images, err := containerUtil.ImageList(workspace.ManagedLabels())
+	if err != nil {
+		return fmt.Errorf("encountered an error attempting to get a list of images: %w", err)
+	}
+
+	legacyImages, err := listLegacyImages(dirs, containers, containerUtil)
+	if err != nil {
+		return err
+	}
+	images = append(images, legacyImages...)
+
+	// a labeled image can also be tagged like a legacy image
+	listed := map[string]bool{}
+	for _, image := range images {
+		ref := fmt.Sprintf("%s:%s", image.Repository, image.Tag)
+		if listed[ref+image.Id] || imageInUse(image, containers) {
+			continue
+		}
+		listed[ref+image.Id] = true
+
+		image := image
+		candidates = append(candidates, pruneCandidate{
+			kind:    "image",
+			name:    ref,
+			size:    image.Size,
+			created: image.CreatedAt,
+			remove: func() error {
+				out, err := containerUtil.RemoveImage(image)
+				if err != nil {
+					return fmt.Errorf("%w | out: %s", err, out)
+				}
+				return nil
+			},
+		})
+	}
+
+	volumes, err := containerUtil.VolumeList(workspace.ManagedLabels())
+	if err != nil {
+		return fmt.Errorf("encountered an error attempting to get a list of volumes: %w", err)
+	}
+
+	for _, volume := range volumes {
+		if usedVolumes[volume.Name] {
+			continue
+		}
+
+		volume := volume
+		candidates = append(candidates, pruneCandidate{
+			kind:    "volume",
+			name:    volume.Name,
+			size:    "-",
+			created: volume.CreatedAt,
+			remove: func() error {
+				out, err := containerUtil.RemoveVolume(volume)
+				if err != nil {
+					return fmt.Errorf("%w | out: %s", err, out)
+				}
+				return nil
+			},
+		})
+	}
+
+	for _, dir := range dirs {
+		if liveWorkspaces[dir.Workspace] {
+			continue
+		}
+
+		// directories are never pruned if that would lose work
+		gitChanges, err := workspace.FindGitChanges(dir.Path)
+		if err != nil || len(gitChanges) > 0 {
+			fmt.Println("Skipping", dir.Path, "because it may contain uncommitted or unpushed git changes")
+			continue
+		}
+
+		size, err := workspace.DirSize(dir.Path)
+		if err != nil {
+			fmt.Println("Skipping", dir.Path, "because its size could not be determined:", err)
+			continue
+		}
+
+		dir := dir
+		candidates = append(candidates, pruneCandidate{
+			kind:    "directory",
+			name:    dir.Path,
+			size:    formatBytes(size),
+			created: dir.ModTime,
+			remove: func() error {
+				return os.RemoveAll(dir.Path)
+			},
+		})
+	}
+
+	if olderThan > 0 {
+		filtered := []pruneCandidate{}
+		for _, candidate := range candidates {
+			// resources without a known age are never old enough
+			if !candidate.created.IsZero() && time.Since(candidate.created) > olderThan {
+				filtered = append(filtered, candidate)
+			}
+		}
+		candidates = filtered
+	}
+
+	if len(candidates) == 0 {
+		fmt.Println("Nothing to prune")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAME\tSIZE\tCREATED")
+	for _, candidate := range candidates {
+		created := "-"
+		if !candidate.created.IsZero() {
+			created = candidate.created.Local().Format(time.RFC822)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", candidate.kind, candidate.name, candidate.size, created)
+	}
+	w.Flush()
+
+	if !pruneForce && !confirm(fmt.Sprintf("Remove these %d resources?", len(candidates))) {
+		return fmt.Errorf("aborted pruning. Use --force to skip confirmation")
+	}
+
+	failed := 0
+	for _, candidate := range candidates {
+		fmt.Println("Removing", candidate.kind+":", candidate.name)
+		err = candidate.remove()
+		if err != nil {
+			fmt.Println("Failed to remove", candidate.kind, candidate.name+":", err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("encountered errors removing %d resources", failed)
+	}
+
+	return nil
+}
+
+// listLegacyImages returns the images built by versions of cade that tagged them with the bare
+// workspace name and didn't label them. They are matched by the names of the workspaces that
+// have a container or a working directory. Images with a registry digest were pulled rather
+// than built so they are never included. Returns an error if any occur during the process
+func listLegacyImages(dirs []workspace.Dir, containers []containerutil.Container, containerUtil containerutil.ContainerUtil) ([]containerutil.Image, error) {
+	workspaceNames := map[string]bool{}
+	for _, dir := range dirs {
+		workspaceNames[strings.ToLower(dir.Workspace)] = true
+	}
+	for _, container := range containers {
+		if workspaceName, ok := workspace.NameFromContainer(container.Name); ok {
+			workspaceNames[strings.ToLower(workspaceName)] = true
+		}
+	}
+
+	images, err := containerUtil.ImageList(nil)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error attempting to get a list of images: %w", err)
+	}
+
+	legacy := []containerutil.Image{}
+	for _, image := range images {
+		if workspaceNames[image.Repository] && image.Tag == "latest" && image.Digest == "" {
+			legacy = append(legacy, image)
+		}
+	}
+
+	return legacy, nil
+}
+
+// imageInUse returns whether or not any of the containers use the image
+func imageInUse(image containerutil.Image, containers []containerutil.Container) bool {
+	for _, container := range containers {
+		if container.Image == "" {
+			continue
+		}
+
+		switch {
+		case container.Image == fmt.Sprintf("%s:%s", image.Repository, image.Tag),
+			container.Image == image.Repository && image.Tag == "latest",
+			container.Image == image.Id,
+			strings.HasPrefix(image.Id, "sha256:"+container.Image):
+			return true
+		}
+	}
+
+	return false
+}
+
+// formatBytes formats a number of bytes in a human readable form, e.g. 1.5MB
+func formatBytes(size int64) string {
+	const unit = 1000
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f%cB", float64(size)/float64(div), "kMGTPE"[exp])
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/everettraven/cade/pkg/workspace"
+	"github.com/spf13/cobra"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [WORKSPACE]",
+	Short: "restores a workspace working directory that was moved to the trash by `cade down --trash`",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return restore(args[0])
+	},
+}
+
+func restore(workspaceName string) error {
+	workspaceDir, err := workspace.Restore(userSettings.WorkspaceRoot, workspaceName, userSettings.TrashRetention)
+	if err != nil {
+		return fmt.Errorf("encountered an error restoring workspace %q: %w", workspaceName, err)
+	}
+
+	fmt.Println("Restored the workspace working directory:", workspaceDir)
+	fmt.Println("Run `cade up` with the workspace config to recreate the workspace container")
+	return nil
+}
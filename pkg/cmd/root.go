@@ -1,23 +1,95 @@
 package cmd
 
-import "github.com/spf13/cobra"
+import (
+	"fmt"
+
+	"github.com/everettraven/cade/pkg/containerutil"
+	"github.com/everettraven/cade/pkg/settings"
+	"github.com/spf13/cobra"
+)
+
+// userSettings are the global user settings. They are
+// loaded before any subcommand is run
+var userSettings *settings.Settings
 
 var rootCmd = &cobra.Command{
 	Use:   "cade",
 	Short: "cade is a CLI tool for using Containers as Development Environments",
 	Example: `
+	## Scaffolding a workspace config and Containerfile for the current project
+	cade init
+
 	## Starting a workspace 
 	cade up https://raw.githubusercontent.com/everettraven/cade/main/example/cadeconfig.yaml
 
+	## Printing a workspace config with everything it extends resolved
+	cade config render cadeconfig.yaml
+
+	## Printing the JSON Schema of workspace configs for editor validation
+	cade config schema > cadeconfig.schema.json
+
+	## Starting a workspace and waiting for its health check to pass
+	cade up --wait cadeconfig.yaml
+
+	## Checking the state and health of a workspace
+	cade status cade-test
+
 	## Starting a terminal in a workspace
 	cade term cade-test
 
+	## Running a command in a workspace
+	cade exec cade-test -- make test
+
+	## Copying files between the host and a workspace
+	cade cp README.md 'docs/*.md' cade-test:docs/
+	cade cp 'cade-test:out/*.log' ./logs
+
+	## Checking on the sync of a workspace with workdir_mode sync
+	cade sync status cade-test
+
+	## Following the output of a workspace container
+	cade logs --follow cade-test
+
+	## Printing the output of the last cade up of a workspace
+	cade logs --up cade-test
+
+	## Installing the dotfiles from the cade settings in a workspace again
+	cade dotfiles apply cade-test
+
+	## Connecting to a workspace with SSH, e.g. from an editor
+	cade ssh-config --write cade-test
+	ssh cade-cade-test
+
 	## Stopping a workspace
 	cade down cade-test
 
+	## Stopping every workspace
+	cade down --all
+
+	## Stopping a workspace and moving its working directory to the trash
+	cade down --trash cade-test
+
+	## Restoring a trashed workspace working directory
+	cade restore cade-test
+
+	## Checking for and applying workspace image updates
+	cade outdated
+	cade upgrade cade-test
+
+	## Removing unused cade images, containers, volumes and directories
+	cade prune --older-than 168h
+
 	## Get the current cade version
 	cade version
 	`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		userSettings, err = settings.Load()
+		if err != nil {
+			return fmt.Errorf("encountered an error loading the cade settings: %w", err)
+		}
+		return nil
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return cmd.Help()
 	},
@@ -28,7 +100,27 @@ func init() {
 	rootCmd.AddCommand(upCmd)
 	rootCmd.AddCommand(downCmd)
 	rootCmd.AddCommand(termCmd)
+	rootCmd.AddCommand(execCmd)
+	rootCmd.AddCommand(logsCmd)
+	rootCmd.AddCommand(cpCmd)
+	rootCmd.AddCommand(syncCmd)
 	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(pruneCmd)
+	rootCmd.AddCommand(outdatedCmd)
+	rootCmd.AddCommand(upgradeCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(dotfilesCmd)
+	rootCmd.AddCommand(sshConfigCmd)
+	rootCmd.AddCommand(sshProxyCmd)
+}
+
+// newContainerUtil returns a ContainerUtil for the
+// runtime configured in the user settings
+func newContainerUtil() (containerutil.ContainerUtil, error) {
+	return containerutil.NewContainerUtil(userSettings.Runtime)
 }
 
 func Execute() error {
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/everettraven/cade/pkg/config"
+	"github.com/everettraven/cade/pkg/containerutil"
+	"github.com/everettraven/cade/pkg/workspace"
+)
+
+// upServices starts the service containers of the workspace on the network
+func upServices(w io.Writer, workspaceName string, network string, services map[string]config.Service, policy config.PullPolicy, containerUtil containerutil.ContainerUtil) error {
+	names := []string{}
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		service := services[name]
+		if service.Image == "" {
+			return fmt.Errorf("the service %q must set an image", name)
+		}
+
+		err := ensureImage(w, service.Image, policy, containerUtil)
+		if err != nil {
+			return err
+		}
+
+		container := containerutil.Container{
+			Name:           workspace.ServiceContainerName(workspaceName, name),
+			Image:          service.Image,
+			Network:        network,
+			NetworkAliases: []string{name},
+			Env:            service.Env,
+			Publish:        service.Ports,
+			Labels:         workspace.Labels(workspaceName),
+		}
+		container.Labels[workspace.ServiceLabel] = name
+
+		fmt.Fprintln(w, "Running the service container:", container.Name)
+		out, err := containerUtil.Run(container, service.Volumes)
+		if err != nil {
+			return fmt.Errorf("encountered an error running the service %q: %w | out: %s", name, err, out)
+		}
+	}
+
+	return nil
+}
+
+// serviceContainers returns the service containers of the workspace
+func serviceContainers(workspaceName string, containerUtil containerutil.ContainerUtil) ([]containerutil.Container, error) {
+	containers, err := containerUtil.ContainerList()
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error attempting to get a list of containers: %w", err)
+	}
+
+	services := []containerutil.Container{}
+	for _, container := range containers {
+		if container.Labels[workspace.WorkspaceLabel] == workspaceName && container.Labels[workspace.ServiceLabel] != "" {
+			services = append(services, container)
+		}
+	}
+
+	return services, nil
+}
+
+// downServices stops and removes the service containers of the workspace
+func downServices(w io.Writer, workspaceName string, containerUtil containerutil.ContainerUtil) error {
+	services, err := serviceContainers(workspaceName, containerUtil)
+	if err != nil {
+		return err
+	}
+
+	for _, container := range services {
+		fmt.Fprintln(w, "Stopping the service container:", container.Name)
+		out, err := containerUtil.StopContainer(container)
+		if err != nil && !errors.Is(err, containerutil.ErrNotFound) && !errors.Is(err, containerutil.ErrNotRunning) {
+			return fmt.Errorf("encountered an error stopping the service container: %w | out: %s", err, out)
+		}
+
+		fmt.Fprintln(w, "Removing the service container:", container.Name)
+		out, err = containerUtil.RemoveContainer(container)
+		if err != nil && !errors.Is(err, containerutil.ErrNotFound) {
+			return fmt.Errorf("encountered an error removing the service container: %w | out: %s", err, out)
+		}
+	}
+
+	return nil
+}
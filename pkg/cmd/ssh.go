@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/everettraven/cade/pkg/containerutil"
+	"github.com/everettraven/cade/pkg/settings"
+	"github.com/everettraven/cade/pkg/sshproxy"
+	"github.com/everettraven/cade/pkg/workspace"
+	"github.com/spf13/cobra"
+)
+
+var writeSSHConfig bool
+
+var sshConfigCmd = &cobra.Command{
+	Use:   "ssh-config [WORKSPACE]",
+	Short: "prints the SSH config entry for connecting to the workspace specified with SSH",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		containerUtil, err := newContainerUtil()
+		if err != nil {
+			return err
+		}
+		return sshConfig(args[0], containerUtil)
+	},
+}
+
+var sshProxyCmd = &cobra.Command{
+	Use:   "ssh-proxy [WORKSPACE]",
+	Short: "serves an SSH session in the workspace specified over stdin and stdout. Used as the ProxyCommand of the entries from `cade ssh-config`",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		containerUtil, err := newContainerUtil()
+		if err != nil {
+			return err
+		}
+		return sshProxy(args[0], containerUtil)
+	},
+}
+
+func init() {
+	sshConfigCmd.Flags().BoolVarP(&writeSSHConfig, "write", "w", false, "add the entry to ~/.ssh/config instead of printing it, replacing the previous entry for the workspace")
+}
+
+func sshConfig(workspaceName string, containerUtil containerutil.ContainerUtil) error {
+	_, _, err := containerUtil.InspectContainer(workspace.ContainerName(workspaceName))
+	if err != nil {
+		return fmt.Errorf("encountered an error getting the workspace container: %w", err)
+	}
+
+	hostKeyPath, knownHostsPath, err := sshPaths()
+	if err != nil {
+		return err
+	}
+
+	hostKey, err := sshproxy.LoadHostKey(hostKeyPath)
+	if err != nil {
+		return err
+	}
+
+	// every workspace shares the host key so a single line trusts all of them
+	knownHosts := sshproxy.KnownHostsLine(sshproxy.HostPrefix+"*", hostKey.PublicKey()) + "\n"
+	err = os.WriteFile(knownHostsPath, []byte(knownHosts), 0600)
+	if err != nil {
+		return fmt.Errorf("encountered an error writing the SSH known hosts file `%s`: %w", knownHostsPath, err)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("encountered an error getting the path of the cade executable: %w", err)
+	}
+
+	entry := sshproxy.ConfigEntry(workspaceName, executable, knownHostsPath)
+	if !writeSSHConfig {
+		fmt.Print(entry)
+		return nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("encountered an error getting the user home directory: %w", err)
+	}
+
+	configPath := filepath.Join(home, ".ssh", "config")
+	err = sshproxy.UpdateConfig(configPath, workspaceName, entry)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Added the workspace to", configPath+". Connect to it with `ssh", sshproxy.HostAlias(workspaceName)+"`")
+	return nil
+}
+
+// sshProxy serves an SSH connection to the workspace over stdin and stdout. Nothing
+// else may be written to stdout because the SSH client reads the connection from it
+func sshProxy(workspaceName string, containerUtil containerutil.ContainerUtil) error {
+	hostKeyPath, _, err := sshPaths()
+	if err != nil {
+		return err
+	}
+
+	hostKey, err := sshproxy.LoadHostKey(hostKeyPath)
+	if err != nil {
+		return err
+	}
+
+	// the forwarded SSH agent and git credentials are available for as long as the connection is open
+	execOpts := containerutil.ExecOptions{}
+	stop, err := startSession(workspaceName, &execOpts, containerUtil)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	server := &sshproxy.Server{
+		ContainerUtil: containerUtil,
+		Container:     workspace.ContainerName(workspaceName),
+		Shell:         userSettings.Shell,
+		Env:           execOpts.Env,
+		HostKey:       hostKey,
+	}
+
+	return server.Serve(sshproxy.NewStdioConn(os.Stdin, os.Stdout))
+}
+
+// sshPaths returns the paths of the SSH host key and known hosts file, which are kept next to the settings file
+func sshPaths() (string, string, error) {
+	settingsPath, err := settings.Path()
+	if err != nil {
+		return "", "", err
+	}
+
+	dir := filepath.Dir(settingsPath)
+	return filepath.Join(dir, "ssh_host_ed25519_key"), filepath.Join(dir, "known_hosts"), nil
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/everettraven/cade/pkg/containerutil"
+	"github.com/everettraven/cade/pkg/workspace"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status [WORKSPACE]",
+	Short: "shows the state and health of a workspace container",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		containerUtil, err := newContainerUtil()
+		if err != nil {
+			return err
+		}
+		return status(args[0], containerUtil)
+	},
+}
+
+func status(workspaceName string, containerUtil containerutil.ContainerUtil) error {
+	container, volumes, err := containerUtil.InspectContainer(workspace.ContainerName(workspaceName))
+	if err != nil {
+		return fmt.Errorf("encountered an error getting the workspace container: %w", err)
+	}
+
+	fmt.Println("Container:", container.Name)
+	fmt.Println("State:", container.State)
+
+	if container.Health != nil {
+		fmt.Println("Health:", container.Health.Status)
+	} else {
+		fmt.Println("Health: no health check")
+	}
+	if container.Healthcheck != nil && container.Labels[workspace.HealthcheckLabel] == "" {
+		fmt.Println("Health check:", container.Healthcheck.Command, "(from the image)")
+	} else if container.Healthcheck != nil {
+		fmt.Println("Health check:", container.Healthcheck.Command)
+	}
+
+	image := container.Labels[workspace.ImageLabel]
+	if image == "" {
+		image = container.Image
+	}
+	fmt.Println("Image:", image)
+	if digest := container.Labels[workspace.ImageDigestLabel]; digest != "" {
+		fmt.Println("Image digest:", digest)
+	}
+
+	if container.Ports != "" {
+		fmt.Println("Ports:", container.Ports)
+	} else {
+		fmt.Println("Ports: none")
+	}
+
+	fmt.Println("Mounts:")
+	for _, volume := range volumes {
+		if volume.ReadOnly {
+			fmt.Println("-", volume.HostPath, "->", volume.MountPath, "(read-only)")
+		} else {
+			fmt.Println("-", volume.HostPath, "->", volume.MountPath)
+		}
+	}
+
+	if probe := lastProbe(container.Health); probe != nil {
+		fmt.Println("Last health check:", probe.End.Local().Format(time.RFC1123), "exited with", probe.ExitCode)
+		if output := strings.TrimSpace(probe.Output); output != "" {
+			fmt.Println("Last health check output:")
+			for _, line := range strings.Split(output, "\n") {
+				fmt.Println("   ", line)
+			}
+		}
+	}
+
+	return nil
+}
+
+// waitHealthy waits until the health check of the workspace container passes. Workspace
+// containers without a health check are ready once they are running. Returns an error if
+// the container stops, becomes unhealthy or is not healthy within the timeout
+func waitHealthy(w io.Writer, workspaceName string, timeout time.Duration, containerUtil containerutil.ContainerUtil) error {
+	fmt.Fprintln(w, "Waiting up to", timeout, "for the workspace container to be healthy")
+	deadline := time.Now().Add(timeout)
+
+	for {
+		container, _, err := containerUtil.InspectContainer(workspace.ContainerName(workspaceName))
+		if err != nil {
+			return fmt.Errorf("encountered an error getting the workspace container: %w", err)
+		}
+
+		switch {
+		case container.State != "running":
+			return fmt.Errorf("the workspace container is %s. Run `cade logs %s` to see its output", container.State, workspaceName)
+		case container.Health == nil:
+			fmt.Fprintln(w, "The workspace container has no health check so it is ready")
+			return nil
+		case container.Health.Status == "healthy":
+			fmt.Fprintln(w, "The workspace container is healthy")
+			return nil
+		case container.Health.Status == "unhealthy":
+			if probe := lastProbe(container.Health); probe != nil {
+				return fmt.Errorf("the workspace container is unhealthy. The last health check exited with %d | out: %s", probe.ExitCode, strings.TrimSpace(probe.Output))
+			}
+			return fmt.Errorf("the workspace container is unhealthy")
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the workspace container was not healthy within %s. Run `cade status %s` to see the output of the health check", timeout, workspaceName)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// lastProbe returns the most recent run of the health check. Nil if it hasn't run
+func lastProbe(health *containerutil.Health) *containerutil.HealthProbe {
+	if health == nil || len(health.Probes) == 0 {
+		return nil
+	}
+
+	return &health.Probes[len(health.Probes)-1]
+}
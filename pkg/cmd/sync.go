@@ -0,0 +1,465 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/everettraven/cade/pkg/archive"
+	"github.com/everettraven/cade/pkg/containerutil"
+	"github.com/everettraven/cade/pkg/filesync"
+	"github.com/everettraven/cade/pkg/workspace"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// syncInterval is how often the working directories are synced. Changes on the host
+	// are synced as soon as they are made, changes in the container are only found by this
+	syncInterval = 2 * time.Second
+	// syncDebounce is how long to wait for more changes on the host before syncing them
+	syncDebounce = 200 * time.Millisecond
+	// syncStopTimeout is how long stopping waits for the final sync
+	syncStopTimeout = 30 * time.Second
+
+	syncStatusFile = "sync-status.json"
+	syncLogFile    = "sync.log"
+	// syncStateFile is kept in the logs directory rather than the runtime directory
+	// so the base of the sync survives a reboot along with the working directory
+	syncStateFile = "sync-state.json"
+)
+
+var resetSync bool
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "commands for syncing the working directory of workspaces with workdir_mode sync",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var syncStartCmd = &cobra.Command{
+	Use:   "start [WORKSPACE]",
+	Short: "starts syncing the working directory of the workspace specified in the background",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		containerUtil, err := newContainerUtil()
+		if err != nil {
+			return err
+		}
+		return startSync(os.Stdout, args[0], resetSync, containerUtil)
+	},
+}
+
+var syncStopCmd = &cobra.Command{
+	Use:   "stop [WORKSPACE]",
+	Short: "syncs the working directory of the workspace specified a final time and stops syncing it",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return stopSync(args[0])
+	},
+}
+
+var syncStatusCmd = &cobra.Command{
+	Use:   "status [WORKSPACE]",
+	Short: "prints whether the working directory of the workspace specified is being synced and any conflicts",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		containerUtil, err := newContainerUtil()
+		if err != nil {
+			return err
+		}
+		return syncStatus(args[0], containerUtil)
+	},
+}
+
+// syncRunCmd is the process started by `cade sync start`
+var syncRunCmd = &cobra.Command{
+	Use:    "run [WORKSPACE]",
+	Short:  "syncs the working directory of the workspace specified until it is stopped",
+	Args:   cobra.ExactArgs(1),
+	Hidden: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		containerUtil, err := newContainerUtil()
+		if err != nil {
+			return err
+		}
+		return runSync(args[0], containerUtil)
+	},
+}
+
+func init() {
+	syncStartCmd.Flags().BoolVar(&resetSync, "reset", false, "forget what was synced before and reconcile the directories as if syncing for the first time")
+	syncCmd.AddCommand(syncStartCmd)
+	syncCmd.AddCommand(syncStopCmd)
+	syncCmd.AddCommand(syncStatusCmd)
+	syncCmd.AddCommand(syncRunCmd)
+}
+
+// syncConfig returns the working directory in the workspace container and the ignore
+// patterns from its labels. Returns an error if its working directory is not synced
+func syncConfig(container *containerutil.Container) (string, filesync.Ignore, error) {
+	containerDir := container.Labels[workspace.SyncLabel]
+	if containerDir == "" {
+		return "", nil, fmt.Errorf("the working directory of the workspace is not synced. Set workdir_mode to sync in the workspace config to sync it")
+	}
+
+	ignore := filesync.Ignore{}
+	if patterns := container.Labels[workspace.SyncIgnoreLabel]; patterns != "" {
+		err := json.Unmarshal([]byte(patterns), &ignore)
+		if err != nil {
+			return "", nil, fmt.Errorf("encountered an error parsing the sync ignore patterns of the workspace container: %w", err)
+		}
+	}
+
+	return containerDir, ignore, nil
+}
+
+// runningSync returns the status of the process syncing the workspace. Returns a
+// nil status if it is not running and an error if any occur during the process
+func runningSync(workspaceName string) (*filesync.Status, error) {
+	status, err := filesync.LoadStatus(filepath.Join(workspace.RuntimeDir(workspaceName), syncStatusFile))
+	if err != nil || status == nil || !processRunning(status.PID) {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// startSync starts the process that syncs the working directory of the workspace. If reset is
+// set what was synced before is forgotten so the directories are reconciled instead
+func startSync(w io.Writer, workspaceName string, reset bool, containerUtil containerutil.ContainerUtil) error {
+	container, _, err := containerUtil.InspectContainer(workspace.ContainerName(workspaceName))
+	if err != nil {
+		return fmt.Errorf("encountered an error getting the workspace container: %w", err)
+	}
+
+	_, _, err = syncConfig(container)
+	if err != nil {
+		return err
+	}
+
+	status, err := runningSync(workspaceName)
+	if err != nil {
+		return err
+	}
+
+	if status != nil {
+		fmt.Fprintln(w, "The working directory of the workspace is already being synced by process", status.PID)
+		return nil
+	}
+
+	runtimeDir, err := workspace.EnsureRuntimeDir(workspaceName)
+	if err != nil {
+		return err
+	}
+
+	statusPath := filepath.Join(runtimeDir, syncStatusFile)
+	err = os.Remove(statusPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("encountered an error removing the previous sync status: %w", err)
+	}
+
+	logsDir := workspace.LogsDir(userSettings.WorkspaceRoot, workspaceName)
+	err = os.MkdirAll(logsDir, 0777)
+	if err != nil {
+		return fmt.Errorf("encountered an error ensuring the directory `%s` exists: %w", logsDir, err)
+	}
+
+	if reset {
+		err = os.Remove(filepath.Join(logsDir, syncStateFile))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("encountered an error removing the sync state: %w", err)
+		}
+	}
+
+	logPath := filepath.Join(logsDir, syncLogFile)
+	log, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("encountered an error opening the sync log `%s`: %w", logPath, err)
+	}
+	defer log.Close()
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("encountered an error getting the path of the cade executable: %w", err)
+	}
+
+	cmd := exec.Command(executable, "sync", "run", workspaceName)
+	cmd.Stdout = log
+	cmd.Stderr = log
+	detachProcess(cmd)
+
+	err = cmd.Start()
+	if err != nil {
+		return fmt.Errorf("encountered an error starting the sync process: %w", err)
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	// the process saves its status once it has started
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case <-exited:
+			return fmt.Errorf("the sync process exited. See the sync log %s for why", logPath)
+		case <-timeout:
+			return fmt.Errorf("the sync process did not start in time. See the sync log %s for why", logPath)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		status, err := filesync.LoadStatus(statusPath)
+		if err == nil && status != nil && status.PID == cmd.Process.Pid {
+			break
+		}
+	}
+
+	fmt.Fprintln(w, "Syncing the working directory", userSettings.WorkspaceDir(workspaceName), "with the workspace in the background. Check on it with `cade sync status", workspaceName+"`")
+	return nil
+}
+
+func stopSync(workspaceName string) error {
+	status, err := runningSync(workspaceName)
+	if err != nil {
+		return err
+	}
+
+	if status == nil {
+		fmt.Println("The working directory of the workspace is not being synced")
+		return nil
+	}
+
+	fmt.Println("Stopping the sync process", status.PID, "after a final sync")
+	err = terminateProcess(status.PID)
+	if err != nil {
+		return fmt.Errorf("encountered an error stopping the sync process: %w", err)
+	}
+
+	deadline := time.Now().Add(syncStopTimeout)
+	for processRunning(status.PID) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the sync process %d did not stop within %s", status.PID, syncStopTimeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return nil
+}
+
+func syncStatus(workspaceName string, containerUtil containerutil.ContainerUtil) error {
+	container, _, err := containerUtil.InspectContainer(workspace.ContainerName(workspaceName))
+	if err != nil {
+		return fmt.Errorf("encountered an error getting the workspace container: %w", err)
+	}
+
+	containerDir, ignore, err := syncConfig(container)
+	if err != nil {
+		return err
+	}
+
+	status, err := filesync.LoadStatus(filepath.Join(workspace.RuntimeDir(workspaceName), syncStatusFile))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case status != nil && processRunning(status.PID):
+		fmt.Println("Sync: running as process", status.PID, "since", status.Started.Local().Format(time.RFC1123))
+	case status != nil:
+		fmt.Println("Sync: stopped. Start it with `cade sync start", workspaceName+"`")
+	default:
+		fmt.Println("Sync: not started. Start it with `cade sync start", workspaceName+"`")
+	}
+
+	fmt.Println("Host directory:", userSettings.WorkspaceDir(workspaceName))
+	fmt.Println("Workspace directory:", containerDir)
+	if len(ignore) > 0 {
+		fmt.Println("Ignored:", strings.Join(ignore, ", "))
+	}
+	fmt.Println("Log:", filepath.Join(workspace.LogsDir(userSettings.WorkspaceRoot, workspaceName), syncLogFile))
+
+	if status == nil {
+		return nil
+	}
+
+	if !status.LastSync.IsZero() {
+		fmt.Println("Last synced:", status.LastSync.Local().Format(time.RFC1123))
+	}
+	if status.LastError != "" {
+		fmt.Println("Last error:", status.LastError)
+	}
+	fmt.Printf("Copied to the workspace: %d, copied to the host: %d, removed: %d\n", status.Pushed, status.Pulled, status.Deleted)
+
+	if len(status.Conflicts) > 0 {
+		fmt.Println("Conflicts (the host version was kept and the workspace version was saved next to it):")
+		for _, conflict := range status.Conflicts {
+			fmt.Printf("  %s -> %s (%s)\n", conflict.Path, conflict.Copy, conflict.Time.Local().Format(time.RFC1123))
+		}
+	}
+
+	return nil
+}
+
+// runSync syncs the working directory of the workspace until the process is asked to exit
+func runSync(workspaceName string, containerUtil containerutil.ContainerUtil) error {
+	containerName := workspace.ContainerName(workspaceName)
+	container, _, err := containerUtil.InspectContainer(containerName)
+	if err != nil {
+		return fmt.Errorf("encountered an error getting the workspace container: %w", err)
+	}
+
+	containerDir, ignore, err := syncConfig(container)
+	if err != nil {
+		return err
+	}
+
+	owner, err := containerOwner(containerName, containerUtil)
+	if err != nil {
+		return err
+	}
+
+	statePath := filepath.Join(workspace.LogsDir(userSettings.WorkspaceRoot, workspaceName), syncStateFile)
+	statusPath := filepath.Join(workspace.RuntimeDir(workspaceName), syncStatusFile)
+
+	base, err := filesync.LoadSnapshot(statePath)
+	if err != nil {
+		return err
+	}
+
+	hostDir := userSettings.WorkspaceDir(workspaceName)
+	syncer := &filesync.Syncer{
+		ContainerUtil: containerUtil,
+		Container:     containerName,
+		HostDir:       hostDir,
+		ContainerDir:  containerDir,
+		Ignore:        ignore,
+		Owner:         owner,
+		Base:          base,
+	}
+
+	status := &filesync.Status{
+		PID:     os.Getpid(),
+		Started: time.Now(),
+	}
+
+	syncOnce := func() {
+		logf := func(format string, args ...interface{}) {
+			fmt.Printf(time.Now().Format(time.RFC3339)+" "+format+"\n", args...)
+		}
+
+		if syncer.Base == nil {
+			logf("Reconciling %s with %s in the workspace", hostDir, containerDir)
+		}
+
+		result, err := syncer.Sync()
+		if err != nil {
+			logf("Failed to sync: %s", err)
+			status.LastError = err.Error()
+		} else {
+			for _, path := range result.Pushed {
+				logf("Copied to the workspace: %s", path)
+			}
+			for _, path := range result.Pulled {
+				logf("Copied to the host: %s", path)
+			}
+			for _, path := range result.Deleted {
+				logf("Removed: %s", path)
+			}
+			for _, conflict := range result.Conflicts {
+				logf("CONFLICT: %s changed on the host and in the workspace. Kept the host version and saved the workspace version to %s", conflict.Path, conflict.Copy)
+			}
+
+			status.LastSync = time.Now()
+			status.LastError = ""
+			status.Pushed += len(result.Pushed)
+			status.Pulled += len(result.Pulled)
+			status.Deleted += len(result.Deleted)
+			status.Conflicts = append(status.Conflicts, result.Conflicts...)
+
+			err = syncer.Base.Save(statePath)
+			if err != nil {
+				logf("Failed to save the sync state: %s", err)
+			}
+		}
+
+		err = status.Save(statusPath)
+		if err != nil {
+			logf("Failed to save the sync status: %s", err)
+		}
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	changes, err := filesync.Watch(hostDir, ignore, stop)
+	if err != nil {
+		fmt.Println("WARNING: failed to watch the host directory for changes, they are synced every", syncInterval, "instead:", err)
+	}
+
+	// the status is saved before the first sync, which can take a while
+	// when reconciling, since `cade sync start` waits for it
+	err = status.Save(statusPath)
+	if err != nil {
+		return err
+	}
+
+	syncOnce()
+
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-signals:
+			syncOnce()
+			return nil
+		case <-changes:
+			// changes usually come in bursts, like saving several files or switching branches
+			time.Sleep(syncDebounce)
+			select {
+			case <-changes:
+			default:
+			}
+			syncOnce()
+		case <-ticker.C:
+			syncOnce()
+		}
+	}
+}
+
+// containerOwner returns the UID and GID of the user of the container
+func containerOwner(containerName string, containerUtil containerutil.ContainerUtil) (archive.Owner, error) {
+	out := &bytes.Buffer{}
+	err := containerUtil.Exec(containerutil.ExecOptions{Stdout: out}, containerName, "sh", "-c", "id -u; id -g")
+	if err != nil {
+		return archive.Owner{}, fmt.Errorf("encountered an error getting the user of the workspace container: %w", err)
+	}
+
+	ids := strings.Fields(out.String())
+	if len(ids) != 2 {
+		return archive.Owner{}, fmt.Errorf("encountered an error getting the user of the workspace container: unexpected output %q", out.String())
+	}
+
+	uid, uidErr := strconv.Atoi(ids[0])
+	gid, gidErr := strconv.Atoi(ids[1])
+	if uidErr != nil || gidErr != nil {
+		return archive.Owner{}, fmt.Errorf("encountered an error getting the user of the workspace container: unexpected output %q", out.String())
+	}
+
+	return archive.Owner{Uid: uid, Gid: gid}, nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/everettraven/cade/pkg/containerutil"
+	"github.com/everettraven/cade/pkg/workspace"
 	"github.com/spf13/cobra"
 )
 
@@ -11,7 +12,11 @@ var termCmd = &cobra.Command{
 	Use:   "term [WORKSPACE]",
 	Short: "starts a terminal in the workspace specified",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return term(args[0], containerutil.NewContainerUtil())
+		containerUtil, err := newContainerUtil()
+		if err != nil {
+			return err
+		}
+		return term(args[0], containerUtil)
 	},
 }
 
@@ -21,9 +26,15 @@ func term(workspaceName string, containerUtil containerutil.ContainerUtil) error
 		Tty:         true,
 	}
 
-	containerName := fmt.Sprintf("cade-workspace-%s", workspaceName)
+	stop, err := startSession(workspaceName, &execOpts, containerUtil)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	containerName := workspace.ContainerName(workspaceName)
 
-	err := containerUtil.Exec(execOpts, containerName, "/bin/sh")
+	err = containerUtil.Exec(execOpts, containerName, userSettings.Shell)
 	if err != nil {
 		return fmt.Errorf("encountered an error starting the workspace terminal: %w", err)
 	}
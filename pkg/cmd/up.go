@@ -1,24 +1,41 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
+	"time"
 
 	"github.com/everettraven/cade/pkg/config"
 	"github.com/everettraven/cade/pkg/containerutil"
+	"github.com/everettraven/cade/pkg/registry"
+	"github.com/everettraven/cade/pkg/workspace"
 	"github.com/spf13/cobra"
 )
 
 var name string
 var build bool
 var contextOverride string
+var pullPolicy string
+var configSHA256 string
+var schemaCheck bool
+var wait bool
+var waitTimeout time.Duration
+
+// upLogRetention is the number of logs of `cade up` kept for each workspace
+const upLogRetention = 10
 
 var upCmd = &cobra.Command{
-	Use:   "up [WORKSPACE]",
+	Use:   "up [CONFIG | -]",
 	Short: "creates a containerized development workspace",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return up(args[0], containerutil.NewContainerUtil())
+		containerUtil, err := newContainerUtil()
+		if err != nil {
+			return err
+		}
+		return up(args[0], containerUtil)
 	},
 }
 
@@ -26,11 +43,34 @@ func init() {
 	upCmd.Flags().StringVarP(&name, "name", "n", "", "sets the workspace name")
 	upCmd.Flags().BoolVarP(&build, "build", "b", false, "force the workspace image to be built")
 	upCmd.Flags().StringVarP(&contextOverride, "context", "c", "", "override the build context")
+	upCmd.Flags().StringVarP(&profile, "profile", "P", "", "the config profile to apply")
+	upCmd.Flags().StringArrayVar(&setValues, "set", nil, "set a config variable, e.g. --set user=me, or pass it from the environment with --set user. Can be repeated")
+	upCmd.Flags().BoolVar(&schemaCheck, "schema-check", false, "validate the workspace config against the config schema before using it")
+	upCmd.Flags().StringVar(&configSHA256, "sha256", "", "the expected sha256 checksum of the workspace config")
+	upCmd.Flags().StringVar(&pullPolicy, "pull", "", "override the pull policy of the prebuilt image. One of: always, if-not-present, never")
+	upCmd.Flags().BoolVar(&wait, "wait", false, "wait for the health check of the workspace container to pass")
+	upCmd.Flags().DurationVar(&waitTimeout, "wait-timeout", 5*time.Minute, "how long --wait waits for the workspace container to be healthy")
 }
 
-func up(workspace string, containerUtil containerutil.ContainerUtil) error {
-	fmt.Println("Parsing the workspace configuration file")
-	workspaceConfig, err := config.ParseWorkspaceConfig(workspace)
+func up(configPath string, containerUtil containerutil.ContainerUtil) (err error) {
+	started := time.Now()
+	// progress is printed to the terminal and the log. Commands cade runs keep
+	// writing to the terminal directly so their output is not logged
+	output := &outputLog{}
+	w := io.MultiWriter(os.Stdout, output)
+	upOutput = output
+	defer func() {
+		upOutput = io.Discard
+		output.Close(err)
+	}()
+
+	set, err := parseSetValues(setValues)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "Parsing the workspace configuration file")
+	workspaceConfig, err := config.ParseWorkspaceConfig(configPath, config.ParseOptions{SHA256: configSHA256, Set: set, Profile: profile, SchemaCheck: schemaCheck})
 	if err != nil {
 		return fmt.Errorf("encountered an error getting the cade config: %w", err)
 	}
@@ -41,7 +81,23 @@ func up(workspace string, containerUtil containerutil.ContainerUtil) error {
 		wkspName = name
 	}
 
-	fmt.Println("Creating containerized workspace:", wkspName)
+	// the log is only opened once the workspace name is known
+	openUpLog(w, output, wkspName, started)
+
+	fmt.Fprintln(w, "Creating containerized workspace:", wkspName)
+
+	if workspaceConfig.Compose != nil {
+		return upCompose(w, wkspName, workspaceConfig, containerUtil)
+	}
+
+	policy := config.PullIfNotPresent
+	if workspaceConfig.PullPolicy != "" {
+		policy = workspaceConfig.PullPolicy
+	}
+
+	if pullPolicy != "" {
+		policy = config.PullPolicy(pullPolicy)
+	}
 
 	if workspaceConfig.Prebuilt == "" || build {
 		context := "."
@@ -53,37 +109,161 @@ func up(workspace string, containerUtil containerutil.ContainerUtil) error {
 			context = contextOverride
 		}
 
-		fmt.Println("Building the image (this could take some time...). Using context:", context)
-		out, err := containerUtil.Build(workspaceConfig.Containerfile, wkspName, context)
+		hash, err := workspace.ImageHash(workspaceConfig.Containerfile, context)
 		if err != nil {
-			return fmt.Errorf("encountered an error building the workspace image: %w | out: %s", err, out)
+			return fmt.Errorf("encountered an error hashing the containerfile and context: %w", err)
 		}
 
-		workspaceConfig.Prebuilt = wkspName
+		imageRef := workspaceConfig.ImageTag
+		if imageRef == "" {
+			imageRef = workspace.ImageRef(userSettings.ImageNamespace, wkspName, hash)
+		}
+
+		// an image_tag stays the same when the containerfile or context
+		// change so the image is only reused if it has the same hash
+		image, err := containerUtil.InspectImage(imageRef)
+		if err == nil && !build && image.Labels[workspace.ImageHashLabel] == hash {
+			fmt.Fprintln(w, "Using the existing image", imageRef, "because the containerfile and context have not changed")
+		} else if err != nil && !errors.Is(err, containerutil.ErrNotFound) {
+			return fmt.Errorf("encountered an error checking if the workspace image exists: %w", err)
+		} else {
+			labels := workspace.Labels(wkspName)
+			labels[workspace.ImageHashLabel] = hash
+
+			fmt.Fprintln(w, "Building the image", imageRef, "(this could take some time...). Using context:", context)
+			out, err := containerUtil.Build(workspaceConfig.Containerfile, imageRef, context, labels)
+			if err != nil {
+				return fmt.Errorf("encountered an error building the workspace image: %w | out: %s", err, out)
+			}
+			upOutput.Write(out)
+		}
+
+		workspaceConfig.Prebuilt = imageRef
+	} else {
+		err = ensureImage(w, workspaceConfig.Prebuilt, policy, containerUtil)
+		if err != nil {
+			return err
+		}
 	}
 
 	container := containerutil.Container{
-		Name:  fmt.Sprintf("cade-workspace-%s", wkspName),
-		Image: workspaceConfig.Prebuilt,
+		Name:   workspace.ContainerName(wkspName),
+		Image:  workspaceConfig.Prebuilt,
+		Labels: workspace.Labels(wkspName),
+	}
+
+	digest, err := imageDigest(workspaceConfig.Prebuilt, containerUtil)
+	if err != nil {
+		return err
 	}
 
-	if workspaceConfig.Network != "" {
-		container.Network = workspaceConfig.Network
+	container.Labels[workspace.ImageLabel] = workspaceConfig.Prebuilt
+	container.Labels[workspace.ImageDigestLabel] = digest
+	if profile != "" {
+		container.Labels[workspace.ProfileLabel] = profile
 	}
 
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("encountered an error getting the user home directory: %w", err)
+	if workspaceConfig.UserMapping != "" {
+		err = workspaceConfig.UserMapping.Validate()
+		if err != nil {
+			return err
+		}
 	}
 
-	baseWorkspaceDir := filepath.Join(home, "cade", "workspaces")
-	fmt.Println("Ensuring the", baseWorkspaceDir, "directory is created")
+	if workspaceConfig.WorkdirMode != "" {
+		err = workspaceConfig.WorkdirMode.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if workspaceConfig.Healthcheck != nil {
+		container.Healthcheck, err = workspaceConfig.Healthcheck.Healthcheck()
+		if err != nil {
+			return err
+		}
+
+		healthcheck, err := json.Marshal(workspaceConfig.Healthcheck)
+		if err != nil {
+			return fmt.Errorf("encountered an error encoding the healthcheck: %w", err)
+		}
+		container.Labels[workspace.HealthcheckLabel] = string(healthcheck)
+	}
+
+	// files the container creates in the working directory are owned
+	// by the host user when the container runs as their UID and GID
+	if workspaceConfig.UserMapping == config.UserMappingAuto {
+		container.User, err = hostUser()
+		if err != nil {
+			return err
+		}
+
+		if workspaceConfig.RemapImageUser {
+			container.Image, err = remapImageUser(w, workspaceConfig.Prebuilt, wkspName, container.User, containerUtil)
+			if err != nil {
+				return err
+			}
+			container.Labels[workspace.UserRemapLabel] = "true"
+		}
+	}
+
+	network := workspaceConfig.Network
+	if network.Name == "" {
+		network.Name = userSettings.Network
+	}
+
+	// networks cade creates for a config may be shared by several workspaces
+	// so they are not labeled with the workspace that created them
+	networkLabels := workspace.ManagedLabels()
+	if len(workspaceConfig.Services) > 0 && network.Name == "" {
+		// services need a network shared with the workspace container to be
+		// reachable by name, so a dedicated one is created if none is configured
+		network.Name = workspace.NetworkName(wkspName)
+		network.CreateIfMissing = true
+		networkLabels = workspace.Labels(wkspName)
+	}
+
+	if network.Name != "" {
+		err = ensureNetwork(w, network, networkLabels, containerUtil)
+		if err != nil {
+			return err
+		}
+	}
+
+	container.Network = network.Name
+	container.NetworkAliases = network.Aliases
+	container.ExtraHosts = network.ExtraHosts
+	container.DNS = network.DNS
+
+	// the services are removed if the workspace container doesn't run
+	// so they don't already exist when `cade up` is run again
+	workspaceRunning := false
+	if len(workspaceConfig.Services) > 0 {
+		defer func() {
+			if err == nil || workspaceRunning {
+				return
+			}
+
+			fmt.Fprintln(w, "Removing the service containers because the workspace container did not run")
+			if cleanupErr := downServices(w, wkspName, containerUtil); cleanupErr != nil {
+				fmt.Fprintln(w, "WARNING: failed to remove the service containers:", cleanupErr)
+			}
+		}()
+
+		err = upServices(w, wkspName, container.Network, workspaceConfig.Services, policy, containerUtil)
+		if err != nil {
+			return err
+		}
+	}
+
+	baseWorkspaceDir := userSettings.WorkspaceRoot
+	fmt.Fprintln(w, "Ensuring the", baseWorkspaceDir, "directory is created")
 	err = os.MkdirAll(baseWorkspaceDir, 0777)
 	if err != nil {
 		return fmt.Errorf("encountered an error ensuring the directory `%s` exists: %w", baseWorkspaceDir, err)
 	}
 
-	workspaceDir := filepath.Join(baseWorkspaceDir, wkspName)
+	workspaceDir := userSettings.WorkspaceDir(wkspName)
 
 	volumes := []containerutil.Volume{
 		{
@@ -94,8 +274,18 @@ func up(workspace string, containerUtil containerutil.ContainerUtil) error {
 
 	volumes = append(volumes, workspaceConfig.Volumes...)
 
+	forwardVolumes, forwardEnv, forwardLabels, err := forwarding(w, wkspName, workspaceConfig)
+	if err != nil {
+		return err
+	}
+	volumes = append(volumes, forwardVolumes...)
+	container.Env = forwardEnv
+	for key, value := range forwardLabels {
+		container.Labels[key] = value
+	}
+
 	if _, err := os.Stat(workspaceDir); os.IsNotExist(err) {
-		fmt.Println("Copying files from container to workspace directory")
+		fmt.Fprintln(w, "Copying files from container to workspace directory")
 		out, err := containerUtil.CopyToHost(container, volumes[0])
 		if err != nil {
 			return fmt.Errorf("encountered an error copying files from container to host: %w | out: %s", err, out)
@@ -104,12 +294,120 @@ func up(workspace string, containerUtil containerutil.ContainerUtil) error {
 		return fmt.Errorf("encountered an error checking if directory `%s` already exists: %w", workspaceDir, err)
 	}
 
-	fmt.Println("Running the workspace container")
+	syncWorkdir := workspaceConfig.WorkdirMode == config.WorkdirModeSync
+	if syncWorkdir {
+		// the working directory is a volume that is synced with the working directory on the host
+		// once the container runs. Docker fills a new volume with the working directory of the image
+		volumeName := workspace.WorkdirVolumeName(wkspName)
+		fmt.Fprintln(w, "Creating the volume for the synced working directory:", volumeName)
+		out, err := containerUtil.CreateVolume(containerutil.ContainerVolume{Name: volumeName, Labels: workspace.Labels(wkspName)})
+		if err != nil {
+			return fmt.Errorf("encountered an error creating the working directory volume: %w | out: %s", err, out)
+		}
+		volumes[0].HostPath = volumeName
+
+		ignore, err := json.Marshal(workspaceConfig.SyncIgnore)
+		if err != nil {
+			return fmt.Errorf("encountered an error encoding the sync ignore patterns: %w", err)
+		}
+		container.Labels[workspace.SyncLabel] = workspaceConfig.Workdir
+		container.Labels[workspace.SyncIgnoreLabel] = string(ignore)
+	}
+
+	fmt.Fprintln(w, "Running the workspace container")
 	out, err := containerUtil.Run(container, volumes)
 	if err != nil {
 		return fmt.Errorf("encountered an error running the workspace image: %w | out: %s", err, out)
 	}
+	workspaceRunning = true
+
+	installDotfiles(w, wkspName, containerUtil)
+
+	if syncWorkdir {
+		// the volume may not be the one that was synced before so the directories are reconciled
+		err = startSync(w, wkspName, true, containerUtil)
+		if err != nil {
+			fmt.Fprintln(w, "WARNING: failed to start syncing the working directory:", err)
+			fmt.Fprintln(w, "Run `cade sync start", wkspName+"` to try again")
+		}
+	}
+
+	if wait {
+		err = waitHealthy(w, wkspName, waitTimeout, containerUtil)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(w, "Workspace ready! The workspace name is", wkspName, "and the mounted working directory is", workspaceDir)
+	return nil
+}
+
+// openUpLog opens the log the output of `cade up` for the workspace is written to and removes
+// the oldest logs of the workspace. Failing to do so is not an error because it doesn't affect the workspace
+func openUpLog(w io.Writer, output *outputLog, workspaceName string, started time.Time) {
+	err := output.Open(workspace.UpLogPath(userSettings.WorkspaceRoot, workspaceName, started))
+	if err != nil {
+		fmt.Fprintln(w, "WARNING: the output is not being logged:", err)
+		return
+	}
+
+	err = workspace.PruneUpLogs(userSettings.WorkspaceRoot, workspaceName, upLogRetention)
+	if err != nil {
+		fmt.Fprintln(w, "WARNING: failed to remove old logs:", err)
+	}
+}
+
+// ensureImage makes sure the image is available locally according to the pull policy
+func ensureImage(w io.Writer, ref string, policy config.PullPolicy, containerUtil containerutil.ContainerUtil) error {
+	err := policy.Validate()
+	if err != nil {
+		return err
+	}
+
+	if policy != config.PullAlways {
+		_, err := containerUtil.InspectImage(ref)
+		if err == nil {
+			return nil
+		} else if !errors.Is(err, containerutil.ErrNotFound) {
+			return fmt.Errorf("encountered an error checking if the image exists: %w", err)
+		}
+
+		if policy == config.PullNever {
+			return fmt.Errorf("the image %s does not exist locally and the pull policy is %s", ref, policy)
+		}
+	}
+
+	parsed, err := registry.ParseReference(ref)
+	if err != nil {
+		return err
+	}
+
+	credentials, err := registry.LoadCredentials(parsed.Registry)
+	if err != nil {
+		return fmt.Errorf("encountered an error loading the registry credentials: %w", err)
+	}
+
+	fmt.Fprintln(w, "Pulling the image", ref)
+	err = containerUtil.PullImage(ref, credentials)
+	if err != nil {
+		return fmt.Errorf("encountered an error pulling the image %s: %w", ref, err)
+	}
 
-	fmt.Println("Workspace ready! The workspace name is", wkspName, "and the mounted working directory is", workspaceDir)
 	return nil
 }
+
+// imageDigest returns the digest of the local image so it can be recorded on the workspace
+// container. Images that were never pushed or pulled have no digest so their ID is used instead
+func imageDigest(ref string, containerUtil containerutil.ContainerUtil) (string, error) {
+	image, err := containerUtil.InspectImage(ref)
+	if err != nil {
+		return "", fmt.Errorf("encountered an error getting the digest of the image %s: %w", ref, err)
+	}
+
+	if image.Digest != "" {
+		return image.Digest, nil
+	}
+
+	return image.Id, nil
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/everettraven/cade/pkg/config"
+	"github.com/everettraven/cade/pkg/containerutil"
+	"github.com/everettraven/cade/pkg/registry"
+	"github.com/everettraven/cade/pkg/workspace"
+	"github.com/spf13/cobra"
+)
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade [WORKSPACE]",
+	Short: "recreates a workspace on the newest version of its image while preserving the working directory",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		containerUtil, err := newContainerUtil()
+		if err != nil {
+			return err
+		}
+		return upgrade(args[0], containerUtil)
+	},
+}
+
+func upgrade(workspaceName string, containerUtil containerutil.ContainerUtil) error {
+	container, volumes, err := containerUtil.InspectContainer(workspace.ContainerName(workspaceName))
+	if err != nil {
+		return fmt.Errorf("encountered an error getting the workspace container: %w", err)
+	}
+
+	if project := container.Labels[workspace.ComposeProjectLabel]; project != "" {
+		return fmt.Errorf("the workspace was created from a compose file. Run `cade up` with the workspace config to upgrade the compose project %s", project)
+	}
+
+	status := workspaceImageStatus(*container, containerUtil)
+	if status.pinned {
+		return fmt.Errorf("the workspace image %s is pinned to a digest. Change the digest in the workspace config and run `cade up` to upgrade it", status.ref)
+	}
+
+	if status.updateRegistry {
+		ref, err := registry.ParseReference(status.ref)
+		if err != nil {
+			return err
+		}
+
+		credentials, err := registry.LoadCredentials(ref.Registry)
+		if err != nil {
+			return fmt.Errorf("encountered an error loading the registry credentials: %w", err)
+		}
+
+		fmt.Println("Pulling the image", status.ref)
+		err = containerUtil.PullImage(status.ref, credentials)
+		if err != nil {
+			return fmt.Errorf("encountered an error pulling the image %s: %w", status.ref, err)
+		}
+	}
+
+	digest, err := imageDigest(status.ref, containerUtil)
+	if err != nil {
+		return err
+	}
+
+	if digest == status.recorded {
+		fmt.Println("Workspace", workspaceName, "is already up to date")
+		return nil
+	}
+
+	fmt.Println("Stopping the workspace container:", container.Name)
+	out, err := containerUtil.StopContainer(*container)
+	if err != nil && !errors.Is(err, containerutil.ErrNotRunning) {
+		return fmt.Errorf("encountered an error stopping the workspace container: %w | out: %s", err, out)
+	}
+
+	fmt.Println("Removing the workspace container:", container.Name)
+	out, err = containerUtil.RemoveContainer(*container)
+	if err != nil {
+		return fmt.Errorf("encountered an error removing the workspace container: %w | out: %s", err, out)
+	}
+
+	// the health check of the inspected container includes one from the
+	// image, which the upgraded image may have changed or removed
+	healthcheck, err := labeledHealthcheck(container.Labels)
+	if err != nil {
+		return err
+	}
+
+	upgraded := containerutil.Container{
+		Name:           container.Name,
+		Image:          status.ref,
+		Network:        container.Network,
+		NetworkAliases: container.NetworkAliases,
+		ExtraHosts:     container.ExtraHosts,
+		DNS:            container.DNS,
+		User:           container.User,
+		Healthcheck:    healthcheck,
+		Env:            forwardingEnv(container.Labels),
+		Labels:         container.Labels,
+	}
+	if upgraded.Labels == nil {
+		upgraded.Labels = workspace.Labels(workspaceName)
+	}
+	upgraded.Labels[workspace.ImageLabel] = status.ref
+	upgraded.Labels[workspace.ImageDigestLabel] = digest
+
+	if upgraded.Labels[workspace.UserRemapLabel] == "true" {
+		upgraded.Image, err = remapImageUser(os.Stdout, status.ref, workspaceName, upgraded.User, containerUtil)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("Running the workspace container on image", status.ref, "with digest", digest)
+	out, err = containerUtil.Run(upgraded, volumes)
+	if err != nil {
+		return fmt.Errorf("encountered an error running the workspace image: %w | out: %s", err, out)
+	}
+
+	fmt.Println("Workspace", workspaceName, "upgraded!")
+	return nil
+}
+
+// labeledHealthcheck returns the health check from the workspace config that the container was
+// created with. Nil if it was created without one. Returns an error if any occur during the process
+func labeledHealthcheck(labels map[string]string) (*containerutil.Healthcheck, error) {
+	value := labels[workspace.HealthcheckLabel]
+	if value == "" {
+		return nil, nil
+	}
+
+	healthcheck := config.HealthcheckConfig{}
+	err := json.Unmarshal([]byte(value), &healthcheck)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error decoding the healthcheck of the workspace container: %w", err)
+	}
+
+	return healthcheck.Healthcheck()
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/everettraven/cade/pkg/containerutil"
+	"github.com/everettraven/cade/pkg/workspace"
+)
+
+// remapUserScript rewrites the UID and GID of the image user to the ones provided, creating
+// the user if the image has none. /etc/passwd and /etc/group are edited directly because
+// minimal images don't have usermod and groupmod
+const remapUserScript = `#!/bin/sh
+set -e
+uid="$1"
+gid="$2"
+user="$3"
+
+case "$user" in
+	''|root|0) user=cade ;;
+	*[!0-9]*) ;;
+	*) user=$(awk -F: -v uid="$user" '$3 == uid { print $1; exit }' /etc/passwd); user=${user:-cade} ;;
+esac
+
+if grep -q "^$user:" /etc/passwd; then
+	home=$(awk -F: -v user="$user" '$1 == user { print $6; exit }' /etc/passwd)
+	oldgid=$(awk -F: -v user="$user" '$1 == user { print $4; exit }' /etc/passwd)
+	awk -F: -v OFS=: -v user="$user" -v uid="$uid" -v gid="$gid" '$1 == user { $3 = uid; $4 = gid } { print }' /etc/passwd > /tmp/cade-passwd
+	cat /tmp/cade-passwd > /etc/passwd
+	rm /tmp/cade-passwd
+else
+	home="/home/$user"
+	echo "$user:x:$uid:$gid::$home:/bin/sh" >> /etc/passwd
+fi
+
+# the primary group of the user gets the GID unless another group already has it
+if ! awk -F: -v gid="$gid" '$3 == gid { found = 1 } END { exit !found }' /etc/group; then
+	if [ -n "$oldgid" ] && [ "$oldgid" != 0 ] && awk -F: -v gid="$oldgid" '$3 == gid { found = 1 } END { exit !found }' /etc/group; then
+		awk -F: -v OFS=: -v old="$oldgid" -v gid="$gid" '$3 == old { $3 = gid } { print }' /etc/group > /tmp/cade-group
+		cat /tmp/cade-group > /etc/group
+		rm /tmp/cade-group
+	else
+		echo "$user:x:$gid:" >> /etc/group
+	fi
+fi
+
+mkdir -p "$home"
+chown -R "$uid:$gid" "$home"
+`
+
+// hostUser returns the UID and GID of the host user in the {uid}:{gid} format
+func hostUser() (string, error) {
+	if runtime.GOOS == "windows" {
+		return "", fmt.Errorf("user_mapping auto is not supported on windows")
+	}
+
+	return fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()), nil
+}
+
+// remapImageUser builds an image from the image where the image user has the UID and GID
+// of the user and returns its reference. It is always built because the build cache makes
+// it cheap and the image has to be rebuilt whenever the image it is built from changes
+func remapImageUser(w io.Writer, ref string, workspaceName string, user string, containerUtil containerutil.ContainerUtil) (string, error) {
+	uid, gid, _ := strings.Cut(user, ":")
+	if uid == "0" {
+		fmt.Fprintln(w, "Not remapping the image user because the host user is root")
+		return ref, nil
+	}
+
+	image, err := containerUtil.InspectImage(ref)
+	if err != nil {
+		return "", fmt.Errorf("encountered an error getting the user of the image %s: %w", ref, err)
+	}
+
+	imageUser, _, _ := strings.Cut(image.User, ":")
+
+	context, err := os.MkdirTemp("", "cade-remap-user-")
+	if err != nil {
+		return "", fmt.Errorf("encountered an error creating the build context to remap the image user: %w", err)
+	}
+	defer os.RemoveAll(context)
+
+	err = os.WriteFile(filepath.Join(context, "remap-user.sh"), []byte(remapUserScript), 0755)
+	if err != nil {
+		return "", fmt.Errorf("encountered an error writing the script to remap the image user: %w", err)
+	}
+
+	containerfile := filepath.Join(context, "Containerfile")
+	instructions := []string{
+		fmt.Sprintf("FROM %s", ref),
+		"USER root",
+		"COPY remap-user.sh /tmp/cade-remap-user.sh",
+		fmt.Sprintf("RUN sh /tmp/cade-remap-user.sh %q %q %q && rm /tmp/cade-remap-user.sh", uid, gid, imageUser),
+	}
+	if image.User != "" {
+		instructions = append(instructions, fmt.Sprintf("USER %s", image.User))
+	}
+
+	err = os.WriteFile(containerfile, []byte(strings.Join(instructions, "\n")+"\n"), 0644)
+	if err != nil {
+		return "", fmt.Errorf("encountered an error writing the containerfile to remap the image user: %w", err)
+	}
+
+	remapped := fmt.Sprintf("%s/%s-user:%s-%s", userSettings.ImageNamespace, strings.ToLower(workspaceName), uid, gid)
+	fmt.Fprintln(w, "Building the image", remapped, "with the image user remapped to", user)
+	out, err := containerUtil.Build(containerfile, remapped, context, workspace.Labels(workspaceName))
+	if err != nil {
+		return "", fmt.Errorf("encountered an error building the image with the remapped user: %w | out: %s", err, out)
+	}
+	upOutput.Write(out)
+
+	return remapped, nil
+}
@@ -1,18 +1,76 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/everettraven/cade/pkg/containerutil"
-	yaml "gopkg.in/yaml.v2"
 )
 
+// PullPolicy determines when the prebuilt image of a workspace is pulled
+type PullPolicy string
+
+const (
+	// PullAlways pulls the image every time the workspace is created
+	PullAlways PullPolicy = "always"
+	// PullIfNotPresent pulls the image only if it does not exist locally
+	PullIfNotPresent PullPolicy = "if-not-present"
+	// PullNever never pulls the image and fails if it does not exist locally
+	PullNever PullPolicy = "never"
+)
+
+// Validate returns an error if the pull policy is not one of the supported policies
+func (p PullPolicy) Validate() error {
+	switch p {
+	case PullAlways, PullIfNotPresent, PullNever:
+		return nil
+	default:
+		return fmt.Errorf("unsupported pull policy %q. must be one of: %s, %s, %s", p, PullAlways, PullIfNotPresent, PullNever)
+	}
+}
+
+// UserMapping determines which user the workspace container runs as
+type UserMapping string
+
+const (
+	// UserMappingNone runs the workspace container as the user of the image
+	UserMappingNone UserMapping = "none"
+	// UserMappingAuto runs the workspace container as the UID and GID of
+	// the host user so files in the working directory are owned by them
+	UserMappingAuto UserMapping = "auto"
+)
+
+// Validate returns an error if the user mapping is not one of the supported mappings
+func (m UserMapping) Validate() error {
+	switch m {
+	case UserMappingNone, UserMappingAuto:
+		return nil
+	default:
+		return fmt.Errorf("unsupported user mapping %q. must be one of: %s, %s", m, UserMappingNone, UserMappingAuto)
+	}
+}
+
+// WorkdirMode determines how the working directory is shared with the workspace container
+type WorkdirMode string
+
+const (
+	// WorkdirModeBind mounts the working directory on the host in the workspace container
+	WorkdirModeBind WorkdirMode = "bind"
+	// WorkdirModeSync keeps the working directory in a container volume and syncs it with
+	// the working directory on the host, which is faster than a bind mount when the
+	// container runtime runs in a VM
+	WorkdirModeSync WorkdirMode = "sync"
+)
+
+// Validate returns an error if the workdir mode is not one of the supported modes
+func (m WorkdirMode) Validate() error {
+	switch m {
+	case WorkdirModeBind, WorkdirModeSync:
+		return nil
+	default:
+		return fmt.Errorf("unsupported workdir mode %q. must be one of: %s, %s", m, WorkdirModeBind, WorkdirModeSync)
+	}
+}
+
 type WorkspaceConfig struct {
 	Prebuilt      string                 `json:"prebuilt" yaml:"prebuilt"`
 	Containerfile string                 `json:"containerfile" yaml:"containerfile"`
@@ -20,49 +78,153 @@ type WorkspaceConfig struct {
 	WorkspaceName string                 `json:"workspace_name" yaml:"workspace_name"`
 	Context       string                 `json:"context" yaml:"context"`
 	Volumes       []containerutil.Volume `json:"volumes" yaml:"volumes"`
-	Network       string                 `json:"network" yaml:"network"`
+	// Network is the network the workspace container uses. It is either
+	// the network name or an object that also configures the network
+	Network NetworkConfig `json:"network,omitempty" yaml:"network,omitempty"`
+	// ImageTag is the reference to tag the built image with. Defaults to
+	// {image_namespace}/{workspace_name}:{hash of the containerfile and context}
+	ImageTag string `json:"image_tag,omitempty" yaml:"image_tag,omitempty"`
+	// PullPolicy determines when the prebuilt image is pulled. Defaults to if-not-present
+	PullPolicy PullPolicy `json:"pull_policy,omitempty" yaml:"pull_policy,omitempty"`
+	// Extends is the source of a config this config is layered on top of
+	Extends string `json:"extends,omitempty" yaml:"extends,omitempty"`
+	// ListMerge sets how lists in this config are merged with the lists of the config
+	// it extends, keyed by the list's dotted path such as volumes. Defaults to append
+	ListMerge map[string]ListMergeStrategy `json:"list_merge,omitempty" yaml:"list_merge,omitempty"`
+	// Vars are the default values of the ${name} variables used in this config
+	Vars map[string]string `json:"vars,omitempty" yaml:"vars,omitempty"`
+	// Profiles are named variants of this config. Each profile is
+	// merged on top of this config when it is selected
+	Profiles map[string]WorkspaceConfig `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+	// Services are containers started alongside the workspace
+	// container on a shared network, keyed by service name
+	Services map[string]Service `json:"services,omitempty" yaml:"services,omitempty"`
+	// Compose is the compose file the workspace is created from. When it is set
+	// the whole compose project is started instead of a single workspace container
+	Compose *ComposeConfig `json:"compose,omitempty" yaml:"compose,omitempty"`
+	// UserMapping determines which user the workspace container runs as. Defaults to none
+	UserMapping UserMapping `json:"user_mapping,omitempty" yaml:"user_mapping,omitempty"`
+	// RemapImageUser rewrites the UID and GID of the image user to the host
+	// user's when the user mapping is auto so it keeps its name and home directory
+	RemapImageUser bool `json:"remap_image_user,omitempty" yaml:"remap_image_user,omitempty"`
+	// ForwardSSHAgent mounts the host SSH agent socket in the workspace container
+	ForwardSSHAgent bool `json:"forward_ssh_agent,omitempty" yaml:"forward_ssh_agent,omitempty"`
+	// ForwardGitConfig mounts the host git config in the workspace container
+	ForwardGitConfig bool `json:"forward_gitconfig,omitempty" yaml:"forward_gitconfig,omitempty"`
+	// ForwardGitCredentials lets git in the workspace container get credentials from
+	// the host credential helpers while a term or exec session is attached
+	ForwardGitCredentials bool `json:"forward_git_credentials,omitempty" yaml:"forward_git_credentials,omitempty"`
+	// ForwardGitCredentialChanges lets git in the workspace container also store and erase credentials
+	// in the host credential helpers when git credentials are forwarded. Otherwise it can only get them
+	ForwardGitCredentialChanges bool `json:"forward_git_credential_changes,omitempty" yaml:"forward_git_credential_changes,omitempty"`
+	// WorkdirMode determines how the working directory is shared with the workspace container. Defaults to bind
+	WorkdirMode WorkdirMode `json:"workdir_mode,omitempty" yaml:"workdir_mode,omitempty"`
+	// SyncIgnore are the patterns of the files that are not synced when the workdir mode is sync.
+	// Patterns without a slash match file names anywhere in the working directory and patterns
+	// with one match paths relative to it
+	SyncIgnore []string `json:"sync_ignore,omitempty" yaml:"sync_ignore,omitempty"`
+	// Healthcheck is the command that checks whether or not the workspace container is ready
+	Healthcheck *HealthcheckConfig `json:"healthcheck,omitempty" yaml:"healthcheck,omitempty"`
+
+	// present is the generic document the config was decoded from. Its keys tell
+	// which values the config sets when it is layered on top of another config
+	present interface{}
 }
 
-// ParseWorkspaceConfig will parse a WorkspaceConfig from the provided source.
-// The path can either be a URL or a local filepath
-func ParseWorkspaceConfig(path string) (*WorkspaceConfig, error) {
-	config := &WorkspaceConfig{}
+// ComposeConfig references the compose file a workspace is created from
+type ComposeConfig struct {
+	// File is the path of the compose file
+	File string `json:"file" yaml:"file"`
+	// Service is the name of the compose service used as the workspace container
+	Service string `json:"service" yaml:"service"`
+}
 
-	var configBytes []byte
-	var err error
+// Service represents a container started alongside the workspace container.
+// It is reachable from the workspace container by its service name
+type Service struct {
+	Image   string                 `json:"image" yaml:"image"`
+	Env     map[string]string      `json:"env,omitempty" yaml:"env,omitempty"`
+	Ports   []string               `json:"ports,omitempty" yaml:"ports,omitempty"`
+	Volumes []containerutil.Volume `json:"volumes,omitempty" yaml:"volumes,omitempty"`
+}
 
-	if strings.Contains(path, "https://") {
-		configBytes, err = fetchWorkspaceConfigFromURL(path)
-	} else {
-		configBytes, err = os.ReadFile(path)
-	}
+// ParseOptions represent options that can be
+// used to configure a ParseWorkspaceConfig function call
+type ParseOptions struct {
+	// The expected sha256 checksum of the config. Not verified if empty
+	SHA256 string
+	// Values for ${name} variables in the config. These take
+	// precedence over environment variables and the config's vars
+	Set map[string]string
+	// The name of the profile to apply. No profile is applied if empty
+	Profile string
+	// Whether or not to validate the config against the schema before parsing it
+	SchemaCheck bool
+}
+
+// ParseWorkspaceConfig will parse a WorkspaceConfig from the provided source.
+// The source can either be a URL, a git:: source, a local filepath or - for stdin.
+// The format is determined by the file extension or, if that is ambiguous, the content.
+// After resolving extends, the selected profile and then the local overlay are merged
+// on top and variables are substituted. The profiles that are not selected are dropped
+func ParseWorkspaceConfig(path string, opts ParseOptions) (*WorkspaceConfig, error) {
+	configBytes, name, err := readSource(path)
 	if err != nil {
 		return nil, fmt.Errorf("encountered an error reading the cade config: %w", err)
 	}
 
-	if filepath.Ext(path) == ".json" {
-		json.Unmarshal(configBytes, &config)
-	} else if filepath.Ext(path) == ".yaml" {
-		yaml.Unmarshal(configBytes, config)
-	} else {
-		return nil, fmt.Errorf("unsupported config file type. must be one of JSON or YAML")
+	if opts.SchemaCheck {
+		err = validateSchema(configBytes, name)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return config, nil
-}
+	if opts.SHA256 != "" {
+		err = verifySHA256(configBytes, opts.SHA256)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-func fetchWorkspaceConfigFromURL(url string) ([]byte, error) {
-	response, err := http.Get(url)
+	config := &WorkspaceConfig{}
+	err = decode(configBytes, detectFormat(name, configBytes), config)
 	if err != nil {
-		return nil, fmt.Errorf("encountered an error fetching the data: %w", err)
+		return nil, err
 	}
 
-	defer response.Body.Close()
+	config, err = resolveExtends(config, path, map[string]bool{sourceKey(path): true})
+	if err != nil {
+		return nil, err
+	}
 
-	bytes, err := ioutil.ReadAll(response.Body)
+	overlay, err := loadLocalOverlay(path)
 	if err != nil {
-		return nil, fmt.Errorf("encountered an error reading the data: %w", err)
+		return nil, err
 	}
 
-	return bytes, nil
+	// the profiles of the local overlay can be selected too, the
+	// rest of it is merged on top of the selected profile
+	if overlay != nil && len(overlay.Profiles) > 0 {
+		profiles := &WorkspaceConfig{Profiles: overlay.Profiles}
+		if keys, ok := overlay.present.(map[string]interface{}); ok {
+			profiles.present = map[string]interface{}{"profiles": keys["profiles"]}
+		}
+
+		config = layer(config, profiles)
+		overlay.Profiles = nil
+	}
+
+	config, err = applyProfile(config, opts.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	if overlay != nil {
+		config = layer(config, overlay)
+	}
+
+	substituteVars(config, opts.Set)
+
+	return config, nil
 }
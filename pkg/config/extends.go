@@ -0,0 +1,264 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// ListMergeStrategy determines how a list in a config is
+// merged with the same list in the config it extends
+type ListMergeStrategy string
+
+const (
+	// ListAppend appends the items of the list to the items of the extended list
+	ListAppend ListMergeStrategy = "append"
+	// ListReplace replaces the extended list with the list
+	ListReplace ListMergeStrategy = "replace"
+)
+
+// resolveExtends layers the config on top of the config it extends, recursively.
+// Sources already in the chain of extended configs are tracked to detect cycles
+func resolveExtends(config *WorkspaceConfig, source string, chain map[string]bool) (*WorkspaceConfig, error) {
+	if config.Extends == "" {
+		config.ListMerge = nil
+		return config, nil
+	}
+
+	for key, strategy := range config.ListMerge {
+		if strategy != ListAppend && strategy != ListReplace {
+			return nil, fmt.Errorf("unsupported list merge strategy %q for %s. must be one of: %s, %s", strategy, key, ListAppend, ListReplace)
+		}
+	}
+
+	baseSource := resolveRelativeSource(source, config.Extends)
+	if chain[sourceKey(baseSource)] {
+		return nil, fmt.Errorf("the config %s extends %s which creates a cycle", source, baseSource)
+	}
+	chain[sourceKey(baseSource)] = true
+
+	configBytes, name, err := readSource(baseSource)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error reading the extended config %s: %w", baseSource, err)
+	}
+
+	base := &WorkspaceConfig{}
+	err = decode(configBytes, detectFormat(name, configBytes), base)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error parsing the extended config %s: %w", baseSource, err)
+	}
+
+	base, err = resolveExtends(base, baseSource, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	return layer(base, config), nil
+}
+
+// layer returns the overlay config merged on top of the base config
+// using the list merge strategies of the overlay
+func layer(base *WorkspaceConfig, overlay *WorkspaceConfig) *WorkspaceConfig {
+	merged := reflect.New(reflect.TypeOf(*base)).Elem()
+	mergeValue(merged, reflect.ValueOf(*base), reflect.ValueOf(*overlay), overlay.presentKeys(), "", overlay.ListMerge)
+
+	layered := merged.Addr().Interface().(*WorkspaceConfig)
+	layered.Extends = ""
+	layered.ListMerge = nil
+	layered.present = mergePresent(base.presentKeys(), overlay.presentKeys())
+
+	return layered
+}
+
+// presentKeys returns the document the config was decoded from. For configs that weren't
+// decoded it is inferred from the values that are set, which can't include zero values
+func (c *WorkspaceConfig) presentKeys() interface{} {
+	if c.present != nil {
+		return c.present
+	}
+
+	return inferPresent(reflect.ValueOf(*c))
+}
+
+// mergeValue sets dst to overlay merged on top of base. present is the document the overlay
+// was decoded from at the same path. Only the keys it has replace the base values, so an
+// overlay can set false, "" or an empty list and keeps the base value of a key it doesn't have.
+// Structs and maps are merged key by key and lists are merged using the strategy for their path
+func mergeValue(dst reflect.Value, base reflect.Value, overlay reflect.Value, present interface{}, fieldPath string, strategies map[string]ListMergeStrategy) {
+	switch dst.Kind() {
+	case reflect.Struct:
+		keys, ok := present.(map[string]interface{})
+		if !ok {
+			// the struct was decoded from another form, such as a network from its name
+			keys, _ = inferPresent(overlay).(map[string]interface{})
+		}
+
+		for i := 0; i < dst.NumField(); i++ {
+			field := dst.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+
+			fieldPresent, ok := keys[fieldName(field)]
+			if !ok {
+				dst.Field(i).Set(base.Field(i))
+				continue
+			}
+
+			mergeValue(dst.Field(i), base.Field(i), overlay.Field(i), fieldPresent, joinFieldPath(fieldPath, field), strategies)
+		}
+	case reflect.Map:
+		if base.IsNil() && overlay.IsNil() {
+			return
+		}
+
+		keys, _ := present.(map[string]interface{})
+		dst.Set(reflect.MakeMap(dst.Type()))
+		for _, key := range base.MapKeys() {
+			dst.SetMapIndex(key, base.MapIndex(key))
+		}
+
+		for _, key := range overlay.MapKeys() {
+			value := overlay.MapIndex(key)
+			if existing := base.MapIndex(key); existing.IsValid() {
+				mergedValue := reflect.New(value.Type()).Elem()
+				mergeValue(mergedValue, existing, value, keys[fmt.Sprint(key.Interface())], fieldPath, strategies)
+				value = mergedValue
+			}
+			dst.SetMapIndex(key, value)
+		}
+	case reflect.Slice:
+		if strategies[fieldPath] == ListReplace || base.Len() == 0 {
+			dst.Set(overlay)
+			return
+		}
+
+		dst.Set(reflect.AppendSlice(reflect.AppendSlice(reflect.MakeSlice(dst.Type(), 0, base.Len()+overlay.Len()), base), overlay))
+	case reflect.Ptr:
+		switch {
+		case overlay.IsNil(), base.IsNil():
+			dst.Set(overlay)
+		default:
+			dst.Set(reflect.New(dst.Type().Elem()))
+			mergeValue(dst.Elem(), base.Elem(), overlay.Elem(), present, fieldPath, strategies)
+		}
+	default:
+		dst.Set(overlay)
+	}
+}
+
+// inferPresent returns a document with the keys of the values that are set in the value
+func inferPresent(value reflect.Value) interface{} {
+	switch value.Kind() {
+	case reflect.Struct:
+		keys := map[string]interface{}{}
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if field.PkgPath != "" || value.Field(i).IsZero() {
+				continue
+			}
+			keys[fieldName(field)] = inferPresent(value.Field(i))
+		}
+		return keys
+	case reflect.Map:
+		keys := map[string]interface{}{}
+		for _, key := range value.MapKeys() {
+			keys[fmt.Sprint(key.Interface())] = inferPresent(value.MapIndex(key))
+		}
+		return keys
+	case reflect.Ptr:
+		if value.IsNil() {
+			return nil
+		}
+		return inferPresent(value.Elem())
+	default:
+		return true
+	}
+}
+
+// mergePresent returns the keys of both documents, so a layered config
+// can be layered again, such as one of its profiles on top of it
+func mergePresent(base interface{}, overlay interface{}) interface{} {
+	baseKeys, baseOK := base.(map[string]interface{})
+	overlayKeys, overlayOK := overlay.(map[string]interface{})
+	if !baseOK || !overlayOK {
+		return overlay
+	}
+
+	merged := map[string]interface{}{}
+	for key, value := range baseKeys {
+		merged[key] = value
+	}
+	for key, value := range overlayKeys {
+		merged[key] = mergePresent(baseKeys[key], value)
+	}
+
+	return merged
+}
+
+// fieldName returns the name of the field in a config
+func fieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+
+	return name
+}
+
+// joinFieldPath appends the yaml name of the field to the dotted path
+func joinFieldPath(fieldPath string, field reflect.StructField) string {
+	name := fieldName(field)
+	if fieldPath == "" {
+		return name
+	}
+
+	return fieldPath + "." + name
+}
+
+// resolveRelativeSource resolves a relative source against the source that references it
+// so that extended configs can be referenced relative to the config extending them
+func resolveRelativeSource(source string, ref string) string {
+	if ref == "-" || strings.HasPrefix(ref, gitSourcePrefix) || strings.Contains(ref, "://") || filepath.IsAbs(ref) {
+		return ref
+	}
+
+	switch {
+	case strings.HasPrefix(source, gitSourcePrefix):
+		repo, subpath, gitRef, err := parseGitSource(strings.TrimPrefix(source, gitSourcePrefix))
+		if err != nil {
+			return ref
+		}
+		return fmt.Sprintf("%s%s//%s?ref=%s", gitSourcePrefix, repo, path.Join(path.Dir(subpath), ref), url.QueryEscape(gitRef))
+	case strings.HasPrefix(source, "https://"), strings.HasPrefix(source, "http://"):
+		base, err := url.Parse(source)
+		if err != nil {
+			return ref
+		}
+		relative, err := url.Parse(ref)
+		if err != nil {
+			return ref
+		}
+		return base.ResolveReference(relative).String()
+	case source == "-":
+		return ref
+	default:
+		return filepath.Join(filepath.Dir(source), ref)
+	}
+}
+
+// sourceKey returns a canonical form of the source used to detect cycles
+func sourceKey(source string) string {
+	if strings.HasPrefix(source, gitSourcePrefix) || strings.Contains(source, "://") || source == "-" {
+		return source
+	}
+
+	if abs, err := filepath.Abs(source); err == nil {
+		return abs
+	}
+
+	return source
+}
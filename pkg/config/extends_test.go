@@ -0,0 +1,120 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/everettraven/cade/pkg/containerutil"
+)
+
+func TestLayer(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		base    string
+		overlay string
+		want    WorkspaceConfig
+	}{
+		{
+			name:    "keys the overlay doesn't have keep the base value",
+			base:    "prebuilt: base\nworkdir: /work\nforward_ssh_agent: true",
+			overlay: "workdir: /overlay",
+			want:    WorkspaceConfig{Prebuilt: "base", Workdir: "/overlay", ForwardSSHAgent: true},
+		},
+		{
+			name:    "false replaces true",
+			base:    "forward_ssh_agent: true\nremap_image_user: true",
+			overlay: "forward_ssh_agent: false",
+			want:    WorkspaceConfig{RemapImageUser: true},
+		},
+		{
+			name:    "an empty string replaces a value",
+			base:    "prebuilt: base\ncontainerfile: Containerfile",
+			overlay: "prebuilt: \"\"",
+			want:    WorkspaceConfig{Containerfile: "Containerfile"},
+		},
+		{
+			name:    "an empty list replaces a list",
+			base:    "sync_ignore: [node_modules]",
+			overlay: "sync_ignore: []\nlist_merge:\n  sync_ignore: replace",
+			want:    WorkspaceConfig{SyncIgnore: []string{}},
+		},
+		{
+			name:    "lists are appended by default",
+			base:    "sync_ignore: [node_modules]",
+			overlay: "sync_ignore: [\"*.o\"]",
+			want:    WorkspaceConfig{SyncIgnore: []string{"node_modules", "*.o"}},
+		},
+		{
+			name:    "nested keys are merged",
+			base:    "network:\n  name: base\n  create_if_missing: true\nhealthcheck:\n  command: test -f /ready\n  retries: 3",
+			overlay: "network:\n  create_if_missing: false\nhealthcheck:\n  retries: 0",
+			want: WorkspaceConfig{
+				Network:     NetworkConfig{Name: "base"},
+				Healthcheck: &HealthcheckConfig{Command: "test -f /ready"},
+			},
+		},
+		{
+			name:    "a network name only replaces the name",
+			base:    "network:\n  name: base\n  aliases: [app]",
+			overlay: "network: overlay",
+			want:    WorkspaceConfig{Network: NetworkConfig{Name: "overlay", Aliases: []string{"app"}}},
+		},
+		{
+			name:    "null removes a value",
+			base:    "healthcheck:\n  command: test -f /ready",
+			overlay: "healthcheck: null",
+			want:    WorkspaceConfig{},
+		},
+		{
+			name:    "map keys are merged",
+			base:    "vars:\n  a: base\n  b: base\nvolumes:\n  - host_path: /a\n    mount_path: /b",
+			overlay: "vars:\n  b: \"\"",
+			want: WorkspaceConfig{
+				Vars:    map[string]string{"a": "base", "b": ""},
+				Volumes: []containerutil.Volume{{HostPath: "/a", MountPath: "/b"}},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			base := &WorkspaceConfig{}
+			if err := decode([]byte(tc.base), formatYAML, base); err != nil {
+				t.Fatal(err)
+			}
+			overlay := &WorkspaceConfig{}
+			if err := decode([]byte(tc.overlay), formatYAML, overlay); err != nil {
+				t.Fatal(err)
+			}
+
+			got := layer(base, overlay)
+			got.present = nil
+			if !reflect.DeepEqual(*got, tc.want) {
+				t.Errorf("expected the config %+v, got %+v", tc.want, *got)
+			}
+		})
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	base := &WorkspaceConfig{}
+	err := decode([]byte("prebuilt: base\nforward_ssh_agent: true\nprofiles:\n  ci:\n    forward_ssh_agent: false\n    sync_ignore: []"), formatYAML, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	extending := &WorkspaceConfig{}
+	err = decode([]byte("workdir: /work\nprofiles:\n  ci:\n    workdir: \"\""), formatYAML, extending)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := applyProfile(layer(base, extending), "ci")
+	if err != nil {
+		t.Fatalf("unexpected error applying the profile: %v", err)
+	}
+
+	got.present = nil
+	want := WorkspaceConfig{Prebuilt: "base", SyncIgnore: []string{}}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("expected the config %+v, got %+v", want, *got)
+	}
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// format is a supported config file format
+type format string
+
+const (
+	formatJSON  format = "json"
+	formatJSONC format = "jsonc"
+	formatYAML  format = "yaml"
+	formatTOML  format = "toml"
+)
+
+// tomlKey matches a bare, quoted or dotted TOML key
+const tomlKey = `(?:[A-Za-z0-9_-]+|"[^"]*"|'[^']*')(?:\s*\.\s*(?:[A-Za-z0-9_-]+|"[^"]*"|'[^']*'))*`
+
+// tomlLine matches the first meaningful line of a TOML document, either a
+// table header on a line of its own or the start of a `key = value` pair
+var tomlLine = regexp.MustCompile(`^(\[\s*` + tomlKey + `\s*\]|\[\[\s*` + tomlKey + `\s*\]\])\s*(#.*)?$|^` + tomlKey + `\s*=`)
+
+// detectFormat determines the format of the config from the extension of its
+// name. If the extension is missing or unknown the format is detected from the content
+func detectFormat(name string, configBytes []byte) format {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return formatJSON
+	case ".jsonc":
+		return formatJSONC
+	case ".yaml", ".yml":
+		return formatYAML
+	case ".toml":
+		return formatTOML
+	}
+
+	for _, line := range strings.Split(string(configBytes), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || line == "---" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "{"), strings.HasPrefix(line, "//"), strings.HasPrefix(line, "/*"):
+			return formatJSONC
+		// a table header can also be a YAML flow sequence so the document has to parse as TOML too
+		case tomlLine.MatchString(line):
+			if _, err := decodeTOML(configBytes); err != nil {
+				return formatYAML
+			}
+			return formatTOML
+		default:
+			return formatYAML
+		}
+	}
+
+	return formatYAML
+}
+
+// decode decodes the config bytes in the provided format into the config
+func decode(configBytes []byte, f format, config *WorkspaceConfig) error {
+	switch f {
+	case formatJSON:
+		err := json.Unmarshal(configBytes, config)
+		if err != nil {
+			return fmt.Errorf("encountered an error parsing the JSON config: %w", err)
+		}
+	case formatJSONC:
+		err := json.Unmarshal(stripJSONC(configBytes), config)
+		if err != nil {
+			return fmt.Errorf("encountered an error parsing the JSONC config: %w", err)
+		}
+	case formatYAML:
+		err := yaml.Unmarshal(configBytes, config)
+		if err != nil {
+			return fmt.Errorf("encountered an error parsing the YAML config: %w", err)
+		}
+	case formatTOML:
+		decoded, err := decodeTOML(configBytes)
+		if err != nil {
+			return fmt.Errorf("encountered an error parsing the TOML config: %w", err)
+		}
+
+		// TOML is decoded through JSON which relies on the
+		// json tags of WorkspaceConfig matching its yaml tags
+		jsonBytes, err := json.Marshal(decoded)
+		if err != nil {
+			return fmt.Errorf("encountered an error converting the TOML config: %w", err)
+		}
+
+		err = json.Unmarshal(jsonBytes, config)
+		if err != nil {
+			return fmt.Errorf("encountered an error converting the TOML config: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported config file type. must be one of JSON, JSONC, YAML or TOML")
+	}
+
+	// the keys of the document tell which values are set when the config is layered on top of another
+	present, err := decodeDocument(configBytes, f)
+	if err != nil {
+		return err
+	}
+	config.present = present
+
+	return nil
+}
+
+// stripJSONC removes comments and trailing commas from JSONC so it can be parsed as JSON
+func stripJSONC(data []byte) []byte {
+	out := &bytes.Buffer{}
+	inString := false
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+
+		if inString {
+			out.WriteByte(c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out.WriteByte(data[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			out.WriteByte(c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				out.WriteByte('\n')
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				i = len(data)
+			} else {
+				i += end + 3
+			}
+		case c == ',':
+			// drop the comma if the next meaningful character closes an object or array
+			next := i + 1
+			for next < len(data) && strings.ContainsRune(" \t\r\n", rune(data[next])) {
+				next++
+			}
+			if next < len(data) && (data[next] == '}' || data[next] == ']') {
+				continue
+			}
+			out.WriteByte(c)
+		default:
+			out.WriteByte(c)
+		}
+	}
+
+	return out.Bytes()
+}
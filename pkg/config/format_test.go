@@ -0,0 +1,107 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/everettraven/cade/pkg/containerutil"
+)
+
+func TestDetectFormat(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		file    string
+		content string
+		want    format
+	}{
+		{name: "extension wins over content", file: "cade.yaml", content: `prebuilt = "image"`, want: formatYAML},
+		{name: "toml extension", file: "cade.toml", content: "prebuilt: image", want: formatTOML},
+		{name: "json", file: "cade", content: `{"prebuilt": "image"}`, want: formatJSONC},
+		{name: "jsonc comment", file: "cade", content: "// comment\n{}", want: formatJSONC},
+		{name: "yaml", file: "cade", content: "# comment\n---\nprebuilt: image", want: formatYAML},
+		{name: "toml key value", file: "cade", content: "# comment\nprebuilt = \"image\"", want: formatTOML},
+		{name: "toml dotted key", file: "cade", content: "compose.file = \"compose.yaml\"", want: formatTOML},
+		{name: "toml table", file: "cade", content: "[compose]\nfile = \"compose.yaml\"", want: formatTOML},
+		{name: "toml table with a comment", file: "cade", content: "[compose] # the compose file\nfile = \"compose.yaml\"", want: formatTOML},
+		{name: "toml array of tables", file: "cade", content: "[[volumes]]\nhost_path = \"/a\"", want: formatTOML},
+		{name: "yaml flow sequence", file: "cade", content: "[a, b]", want: formatYAML},
+		{name: "yaml flow sequence with spaces", file: "cade", content: "[ a b ]\n- c", want: formatYAML},
+		{name: "yaml flow sequence that looks like a table", file: "cade", content: "[build]\n- c", want: formatYAML},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := detectFormat(tc.file, []byte(tc.content))
+			if got != tc.want {
+				t.Errorf("expected the format %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDecodeTOML(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content string
+		want    WorkspaceConfig
+		wantErr bool
+	}{
+		{
+			name:    "key values",
+			content: "prebuilt = \"image\" # a comment\nremap_image_user = true\nsync_ignore = [\"node_modules\", \"*.o\"]",
+			want:    WorkspaceConfig{Prebuilt: "image", RemapImageUser: true, SyncIgnore: []string{"node_modules", "*.o"}},
+		},
+		{
+			name:    "tables",
+			content: "[compose]\nfile = \"compose.yaml\"\nservice = \"app\"\n\n[vars]\nuser = \"me\"",
+			want:    WorkspaceConfig{Compose: &ComposeConfig{File: "compose.yaml", Service: "app"}, Vars: map[string]string{"user": "me"}},
+		},
+		{
+			name:    "arrays of tables",
+			content: "[[volumes]]\nhost_path = \"/a\"\nmount_path = \"/b\"\n\n[[volumes]]\nhost_path = \"/c\"\nmount_path = \"/d\"\nread_only = true",
+			want: WorkspaceConfig{Volumes: []containerutil.Volume{
+				{HostPath: "/a", MountPath: "/b"},
+				{HostPath: "/c", MountPath: "/d", ReadOnly: true},
+			}},
+		},
+		{
+			name:    "dotted keys",
+			content: "compose.file = \"compose.yaml\"\nvars.\"user name\" = 'me'",
+			want:    WorkspaceConfig{Compose: &ComposeConfig{File: "compose.yaml"}, Vars: map[string]string{"user name": "me"}},
+		},
+		{
+			name:    "inline tables",
+			content: "healthcheck = { command = \"test -f /ready\", retries = 3 }",
+			want:    WorkspaceConfig{Healthcheck: &HealthcheckConfig{Command: "test -f /ready", Retries: 3}},
+		},
+		{
+			name:    "multi-line strings",
+			content: "[healthcheck]\ncommand = \"\"\"\ntest -f /ready && \\\n  test -f /started\"\"\"\ninterval = '''10s'''",
+			want:    WorkspaceConfig{Healthcheck: &HealthcheckConfig{Command: "test -f /ready && test -f /started", Interval: "10s"}},
+		},
+		{name: "missing value", content: "prebuilt =", wantErr: true},
+		{name: "unterminated string", content: "prebuilt = \"image", wantErr: true},
+		{name: "duplicate key", content: "prebuilt = \"a\"\nprebuilt = \"b\"", wantErr: true},
+		{name: "duplicate table", content: "[compose]\nfile = \"a\"\n[compose]\nservice = \"b\"", wantErr: true},
+		{name: "two values on a line", content: "prebuilt = \"a\" workdir = \"/b\"", wantErr: true},
+		{name: "wrong type", content: "workdir = 1", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := WorkspaceConfig{}
+			err := decode([]byte(tc.content), formatTOML, &got)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error decoding the TOML config: %v", err)
+			}
+
+			got.present = nil
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected the config %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/everettraven/cade/pkg/containerutil"
+)
+
+// HealthcheckConfig represents the command that checks whether or not
+// the workspace container is ready to be used
+type HealthcheckConfig struct {
+	// Command is the shell command run in the workspace container.
+	// The container is healthy while it exits with 0
+	Command string `json:"command" yaml:"command"`
+	// Interval is the time between runs of the command, such as 10s. Defaults to the runtime default
+	Interval string `json:"interval,omitempty" yaml:"interval,omitempty"`
+	// Retries is the number of failed runs in a row after which
+	// the container is unhealthy. Defaults to the runtime default
+	Retries int `json:"retries,omitempty" yaml:"retries,omitempty"`
+	// StartPeriod is the time the container has to start, such as 1m, during
+	// which failed runs are not counted. Defaults to the runtime default
+	StartPeriod string `json:"start_period,omitempty" yaml:"start_period,omitempty"`
+}
+
+// Healthcheck returns the health check the workspace container is run with.
+// Returns an error if the health check is not valid
+func (h HealthcheckConfig) Healthcheck() (*containerutil.Healthcheck, error) {
+	if h.Command == "" {
+		return nil, fmt.Errorf("the healthcheck must have a command")
+	}
+
+	if h.Retries < 0 {
+		return nil, fmt.Errorf("the healthcheck retries must not be negative")
+	}
+
+	interval, err := parseHealthcheckDuration("interval", h.Interval)
+	if err != nil {
+		return nil, err
+	}
+
+	startPeriod, err := parseHealthcheckDuration("start_period", h.StartPeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	return &containerutil.Healthcheck{
+		Command:     h.Command,
+		Interval:    interval,
+		Retries:     h.Retries,
+		StartPeriod: startPeriod,
+	}, nil
+}
+
+// parseHealthcheckDuration parses the duration of the healthcheck field. Zero if it is empty
+func parseHealthcheckDuration(field string, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("the healthcheck %s %q is not a valid duration such as 30s", field, value)
+	}
+
+	return duration, nil
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// netrcCredentials returns the login and password for the host from the netrc file
+// at $NETRC or ~/.netrc. Returns empty strings if there is no matching entry
+func netrcCredentials(host string) (string, string, error) {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", nil
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+
+	netrcBytes, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", "", nil
+	} else if err != nil {
+		return "", "", fmt.Errorf("encountered an error reading the netrc file `%s`: %w", path, err)
+	}
+
+	var login, password string
+	var matched, inDefault bool
+	fields := strings.Fields(string(netrcBytes))
+	for i := 0; i < len(fields); i++ {
+		next := ""
+		if i+1 < len(fields) {
+			next = fields[i+1]
+		}
+
+		switch fields[i] {
+		case "machine":
+			// a later entry can't override one that already matched
+			if matched && !inDefault {
+				return login, password, nil
+			}
+			matched, inDefault = next == host, false
+			if matched {
+				login, password = "", ""
+			}
+			i++
+		case "default":
+			if matched {
+				return login, password, nil
+			}
+			matched, inDefault = true, true
+		case "login":
+			if matched {
+				login = next
+			}
+			i++
+		case "password":
+			if matched {
+				password = next
+			}
+			i++
+		case "account", "macdef":
+			i++
+		}
+	}
+
+	if matched {
+		return login, password, nil
+	}
+
+	return "", "", nil
+}
@@ -0,0 +1,62 @@
+package config
+
+import "encoding/json"
+
+// NetworkConfig represents the network the workspace container uses.
+// In a config it can be set to either the network name or an object
+type NetworkConfig struct {
+	// Name is the name of the network
+	Name string `json:"name" yaml:"name"`
+	// Driver is the driver used when the network is created. Defaults to the runtime default
+	Driver string `json:"driver,omitempty" yaml:"driver,omitempty"`
+	// CreateIfMissing creates the network if it does not exist. Networks
+	// created by cade are removed when the last workspace using them is removed
+	CreateIfMissing bool `json:"create_if_missing,omitempty" yaml:"create_if_missing,omitempty"`
+	// Aliases are additional names the workspace container can be reached by on the network
+	Aliases []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	// ExtraHosts are added to the hosts file of the workspace container, in the {host}:{ip} format
+	ExtraHosts []string `json:"extra_hosts,omitempty" yaml:"extra_hosts,omitempty"`
+	// DNS are the DNS servers the workspace container uses
+	DNS []string `json:"dns,omitempty" yaml:"dns,omitempty"`
+}
+
+// networkConfig has the fields of NetworkConfig without its
+// unmarshal methods so they can decode the object form
+type networkConfig NetworkConfig
+
+// UnmarshalYAML decodes the network from either its name or an object
+func (n *NetworkConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		*n = NetworkConfig{Name: name}
+		return nil
+	}
+
+	return unmarshal((*networkConfig)(n))
+}
+
+// UnmarshalJSON decodes the network from either its name or an object
+func (n *NetworkConfig) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*n = NetworkConfig{Name: name}
+		return nil
+	}
+
+	return json.Unmarshal(data, (*networkConfig)(n))
+}
+
+// IsZero returns whether or not no network is configured. The
+// network is then left out when the config is encoded
+func (n NetworkConfig) IsZero() bool {
+	return n.Name == "" && n.Driver == "" && !n.CreateIfMissing && len(n.Aliases) == 0 && len(n.ExtraHosts) == 0 && len(n.DNS) == 0
+}
+
+// MarshalYAML encodes the network as its name when nothing else is configured
+func (n NetworkConfig) MarshalYAML() (interface{}, error) {
+	if n.Driver == "" && !n.CreateIfMissing && len(n.Aliases) == 0 && len(n.ExtraHosts) == 0 && len(n.DNS) == 0 {
+		return n.Name, nil
+	}
+
+	return networkConfig(n), nil
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// applyProfile merges the profile with the provided name on top of the config. The profiles
+// are dropped from the returned config, even when the name is empty and none is applied
+func applyProfile(config *WorkspaceConfig, name string) (*WorkspaceConfig, error) {
+	if name == "" {
+		base := *config
+		base.Profiles = nil
+		return &base, nil
+	}
+
+	profile, ok := config.Profiles[name]
+	if !ok {
+		names := []string{}
+		for profileName := range config.Profiles {
+			names = append(names, profileName)
+		}
+		sort.Strings(names)
+
+		return nil, fmt.Errorf("the profile %q does not exist in the config. available profiles: [%s]", name, strings.Join(names, ", "))
+	}
+
+	if profile.Extends != "" || len(profile.Profiles) > 0 {
+		return nil, fmt.Errorf("the profile %q can't set extends or profiles", name)
+	}
+
+	keys, _ := config.presentKeys().(map[string]interface{})
+	if profiles, ok := keys["profiles"].(map[string]interface{}); ok {
+		profile.present = profiles[name]
+	}
+
+	// the profile is resolved so the other profiles are no longer relevant
+	base := *config
+	base.Profiles = nil
+
+	return layer(&base, &profile), nil
+}
@@ -0,0 +1,465 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/everettraven/cade/pkg/containerutil"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// schemaDraft is the JSON Schema draft the generated schema conforms to
+const schemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// Schema represents the subset of JSON Schema used to describe workspace configs
+type Schema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Ref         string `json:"$ref,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type,omitempty"`
+	// AdditionalProperties is either a bool or a *Schema
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Definitions          map[string]*Schema `json:"definitions,omitempty"`
+}
+
+// schemaDescriptions are the descriptions of the config fields, keyed by {type}.{field}
+var schemaDescriptions = map[string]string{
+	"WorkspaceConfig":                             "A cade workspace config",
+	"WorkspaceConfig.Prebuilt":                    "The image to create the workspace from. The containerfile is built instead if this is not set",
+	"WorkspaceConfig.Containerfile":               "The path of the containerfile to build the workspace image from",
+	"WorkspaceConfig.Workdir":                     "The working directory in the container that is mounted from the host",
+	"WorkspaceConfig.WorkspaceName":               "The name of the workspace",
+	"WorkspaceConfig.Context":                     "The build context of the containerfile. Defaults to the current directory",
+	"WorkspaceConfig.Volumes":                     "Additional host paths to mount in the workspace container",
+	"WorkspaceConfig.Network":                     "The network the workspace container uses. Either the network name or an object",
+	"WorkspaceConfig.ImageTag":                    "The reference to tag the built image with. Defaults to {image_namespace}/{workspace_name}:{content hash}",
+	"WorkspaceConfig.PullPolicy":                  "When the prebuilt image is pulled. Defaults to if-not-present",
+	"WorkspaceConfig.Extends":                     "The path or URL of a config this config is layered on top of",
+	"WorkspaceConfig.ListMerge":                   "How lists are merged with the lists of the extended config, keyed by the list's dotted path. Defaults to append",
+	"WorkspaceConfig.Vars":                        "The default values of the ${name} variables used in this config. ${name:-default} falls back to the default when the variable isn't set, a ${name} that isn't declared is left as is and $$ is a literal $",
+	"WorkspaceConfig.Profiles":                    "Named variants of this config that are merged on top of it when selected with --profile",
+	"WorkspaceConfig.Services":                    "Containers started alongside the workspace container on a shared network, keyed by service name",
+	"WorkspaceConfig.Compose":                     "The compose file the workspace is created from. The whole compose project is started when it is set",
+	"WorkspaceConfig.UserMapping":                 "Which user the workspace container runs as. auto runs it as the UID and GID of the host user. Defaults to none",
+	"WorkspaceConfig.RemapImageUser":              "Rewrite the UID and GID of the image user to the host user's when user_mapping is auto",
+	"WorkspaceConfig.ForwardSSHAgent":             "Mount the host SSH agent socket in the workspace container and set SSH_AUTH_SOCK",
+	"WorkspaceConfig.ForwardGitConfig":            "Mount the host git config in the workspace container",
+	"WorkspaceConfig.ForwardGitCredentials":       "Let git in the workspace container get credentials from the host credential helpers while a term or exec session is attached",
+	"WorkspaceConfig.ForwardGitCredentialChanges": "Let git in the workspace container also store and erase credentials in the host credential helpers when forward_git_credentials is set",
+	"WorkspaceConfig.WorkdirMode":                 "How the working directory is shared with the workspace container. sync keeps it in a container volume synced with the host. Defaults to bind",
+	"WorkspaceConfig.SyncIgnore":                  "Patterns of the files that are not synced when workdir_mode is sync. Patterns without a slash match file names anywhere",
+	"WorkspaceConfig.Healthcheck":                 "The command that checks whether or not the workspace container is ready. Shown by cade status and waited for by cade up --wait",
+	"ComposeConfig":                               "The compose file a workspace is created from",
+	"ComposeConfig.File":                          "The path of the compose file",
+	"ComposeConfig.Service":                       "The name of the compose service used as the workspace container",
+	"HealthcheckConfig":                           "The command that checks whether or not the workspace container is ready",
+	"HealthcheckConfig.Command":                   "The shell command run in the workspace container. The container is healthy while it exits with 0",
+	"HealthcheckConfig.Interval":                  "The time between runs of the command, such as 10s. Defaults to the runtime default",
+	"HealthcheckConfig.Retries":                   "The number of failed runs in a row after which the container is unhealthy. Defaults to the runtime default",
+	"HealthcheckConfig.StartPeriod":               "The time the container has to start, such as 1m, during which failed runs are not counted. Defaults to the runtime default",
+	"Service":                                     "A container started alongside the workspace container and reachable by its service name",
+	"Service.Image":                               "The image of the service",
+	"Service.Env":                                 "Environment variables set in the service container",
+	"Service.Ports":                               "Ports published to the host, in the {host port}:{container port} format",
+	"Service.Volumes":                             "Host paths or named volumes mounted in the service container",
+	"NetworkConfig":                               "The network the workspace container uses",
+	"NetworkConfig.Name":                          "The name of the network",
+	"NetworkConfig.Driver":                        "The driver used when the network is created",
+	"NetworkConfig.CreateIfMissing":               "Create the network if it does not exist. It is removed when the last workspace using it is removed",
+	"NetworkConfig.Aliases":                       "Additional names the workspace container can be reached by on the network",
+	"NetworkConfig.ExtraHosts":                    "Entries added to the hosts file of the workspace container, in the {host}:{ip} format",
+	"NetworkConfig.DNS":                           "The DNS servers the workspace container uses",
+	"Volume":                                      "A host path mounted in the workspace container",
+	"Volume.HostPath":                             "The path on the host",
+	"Volume.MountPath":                            "The path in the container",
+	"Volume.ReadOnly":                             "Mount the path read-only",
+}
+
+// schemaEnums are the allowed values of enum types
+var schemaEnums = map[reflect.Type][]string{
+	reflect.TypeOf(PullPolicy("")):        {string(PullAlways), string(PullIfNotPresent), string(PullNever)},
+	reflect.TypeOf(ListMergeStrategy("")): {string(ListAppend), string(ListReplace)},
+	reflect.TypeOf(UserMapping("")):       {string(UserMappingNone), string(UserMappingAuto)},
+	reflect.TypeOf(WorkdirMode("")):       {string(WorkdirModeBind), string(WorkdirModeSync)},
+}
+
+// schemaRequired are the required fields of each type, by their json names
+var schemaRequired = map[reflect.Type][]string{
+	reflect.TypeOf(containerutil.Volume{}): {"host_path", "mount_path"},
+	reflect.TypeOf(Service{}):              {"image"},
+	reflect.TypeOf(NetworkConfig{}):        {"name"},
+	reflect.TypeOf(ComposeConfig{}):        {"file", "service"},
+	reflect.TypeOf(HealthcheckConfig{}):    {"command"},
+}
+
+// schemaShorthands are the types that can also be set to a string in a config
+var schemaShorthands = map[reflect.Type]bool{
+	reflect.TypeOf(NetworkConfig{}): true,
+}
+
+// GenerateSchema generates the JSON Schema of the WorkspaceConfig
+func GenerateSchema() *Schema {
+	definitions := map[string]*Schema{}
+	ref := schemaFor(reflect.TypeOf(WorkspaceConfig{}), "", definitions)
+
+	// keywords next to $ref are ignored so the required fields are a separate schema
+	return &Schema{
+		Schema:      schemaDraft,
+		Title:       "cade workspace config",
+		Definitions: definitions,
+		AllOf: []*Schema{
+			{Ref: ref.Ref},
+			// configs that extend another config get the required fields from it
+			// and the compose file defines the image of compose workspaces
+			{
+				AnyOf: []*Schema{
+					{Required: []string{"extends"}},
+					{Required: []string{"compose"}},
+					{
+						Required: []string{"workdir"},
+						AnyOf: []*Schema{
+							{Required: []string{"prebuilt"}},
+							{Required: []string{"containerfile"}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// schemaFor returns the schema of the type. Structs are added to the definitions and referenced
+func schemaFor(t reflect.Type, description string, definitions map[string]*Schema) *Schema {
+	if enum, ok := schemaEnums[t]; ok {
+		return &Schema{Type: "string", Enum: enum, Description: description}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string", Description: description}
+	case reflect.Bool:
+		return &Schema{Type: "boolean", Description: description}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Description: description}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Description: description}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: schemaFor(t.Elem(), "", definitions), Description: description}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaFor(t.Elem(), "", definitions), Description: description}
+	case reflect.Ptr:
+		return schemaFor(t.Elem(), description, definitions)
+	case reflect.Struct:
+		if _, ok := definitions[t.Name()]; !ok {
+			definition := &Schema{
+				Type:                 "object",
+				Description:          schemaDescriptions[t.Name()],
+				Properties:           map[string]*Schema{},
+				AdditionalProperties: false,
+				Required:             schemaRequired[t],
+			}
+			// added before the fields so recursive types reference it instead of looping
+			definitions[t.Name()] = definition
+
+			for i := 0; i < t.NumField(); i++ {
+				field := t.Field(i)
+				name := strings.Split(field.Tag.Get("json"), ",")[0]
+				if field.PkgPath != "" || name == "" || name == "-" {
+					continue
+				}
+
+				definition.Properties[name] = schemaFor(field.Type, schemaDescriptions[t.Name()+"."+field.Name], definitions)
+			}
+		}
+
+		if schemaShorthands[t] {
+			return &Schema{
+				Description: description,
+				AnyOf: []*Schema{
+					{Type: "string"},
+					{Ref: "#/definitions/" + t.Name()},
+				},
+			}
+		}
+
+		return &Schema{Ref: "#/definitions/" + t.Name(), Description: description}
+	default:
+		return &Schema{Description: description}
+	}
+}
+
+// ValidateSchema validates the config at the source against the generated schema
+// before anything it extends is resolved. Returns an error describing every violation
+func ValidateSchema(source string) error {
+	configBytes, name, err := readSource(source)
+	if err != nil {
+		return fmt.Errorf("encountered an error reading the cade config: %w", err)
+	}
+
+	return validateSchema(configBytes, name)
+}
+
+// validateSchema validates the config bytes against the generated schema
+func validateSchema(configBytes []byte, name string) error {
+	document, err := decodeDocument(configBytes, detectFormat(name, configBytes))
+	if err != nil {
+		return err
+	}
+
+	schema := GenerateSchema()
+	violations := schema.validate(document, "", schema)
+	if len(violations) > 0 {
+		return fmt.Errorf("the config does not match the schema:\n  %s", strings.Join(violations, "\n  "))
+	}
+
+	return nil
+}
+
+// decodeDocument decodes the config bytes into generic values
+func decodeDocument(configBytes []byte, f format) (interface{}, error) {
+	var document interface{}
+	var err error
+
+	switch f {
+	case formatJSON:
+		err = json.Unmarshal(configBytes, &document)
+	case formatJSONC:
+		err = json.Unmarshal(stripJSONC(configBytes), &document)
+	case formatYAML:
+		err = yaml.Unmarshal(configBytes, &document)
+		if err == nil {
+			document, err = normalizeYAML(document)
+		}
+	case formatTOML:
+		document, err = decodeTOML(configBytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error parsing the %s config: %w", strings.ToUpper(string(f)), err)
+	}
+
+	if document == nil {
+		document = map[string]interface{}{}
+	}
+
+	return document, nil
+}
+
+// normalizeYAML converts the map[interface{}]interface{} values
+// produced by the YAML decoder into map[string]interface{}
+func normalizeYAML(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		normalized := map[string]interface{}{}
+		for key, val := range v {
+			n, err := normalizeYAML(val)
+			if err != nil {
+				return nil, err
+			}
+			normalized[fmt.Sprint(key)] = n
+		}
+		return normalized, nil
+	case []interface{}:
+		normalized := make([]interface{}, len(v))
+		for i, val := range v {
+			n, err := normalizeYAML(val)
+			if err != nil {
+				return nil, err
+			}
+			normalized[i] = n
+		}
+		return normalized, nil
+	default:
+		return v, nil
+	}
+}
+
+// validate validates the value against the schema and returns the violations.
+// The root schema is used to resolve references. Like draft-07, the other
+// keywords of a schema with a reference are ignored
+func (s *Schema) validate(value interface{}, path string, root *Schema) []string {
+	violations := []string{}
+	location := path
+	if location == "" {
+		location = "config"
+	}
+
+	if s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/definitions/")
+		if definition, ok := root.Definitions[name]; ok {
+			return definition.validate(value, path, root)
+		}
+		return violations
+	}
+
+	for _, option := range s.AllOf {
+		violations = append(violations, option.validate(value, path, root)...)
+	}
+
+	if s.Type != "" && !matchesType(value, s.Type) {
+		return append(violations, fmt.Sprintf("%s: expected %s but got %s", location, s.Type, describeType(value)))
+	}
+
+	if len(s.Enum) > 0 {
+		str, _ := value.(string)
+		found := false
+		for _, allowed := range s.Enum {
+			found = found || str == allowed
+		}
+		if !found {
+			violations = append(violations, fmt.Sprintf("%s: %v is not one of: %s", location, value, strings.Join(s.Enum, ", ")))
+		}
+	}
+
+	if object, ok := value.(map[string]interface{}); ok {
+		for _, required := range s.Required {
+			if _, ok := object[required]; !ok {
+				violations = append(violations, fmt.Sprintf("%s: missing required property %q", location, required))
+			}
+		}
+
+		keys := []string{}
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			propertyPath := joinPath(path, key)
+			if property, ok := s.Properties[key]; ok {
+				violations = append(violations, property.validate(object[key], propertyPath, root)...)
+				continue
+			}
+
+			switch additional := s.AdditionalProperties.(type) {
+			case bool:
+				if !additional {
+					violations = append(violations, fmt.Sprintf("%s: unknown property %q", location, key))
+				}
+			case *Schema:
+				violations = append(violations, additional.validate(object[key], propertyPath, root)...)
+			}
+		}
+	}
+
+	if array, ok := value.([]interface{}); ok && s.Items != nil {
+		for i, item := range array {
+			violations = append(violations, s.Items.validate(item, fmt.Sprintf("%s[%d]", location, i), root)...)
+		}
+	}
+
+	if len(s.AnyOf) > 0 {
+		matched := false
+		// the violations of the only option with the type of the value
+		// are more useful than describing every option
+		var typed [][]string
+		for _, option := range s.AnyOf {
+			optionViolations := option.validate(value, path, root)
+			if len(optionViolations) == 0 {
+				matched = true
+				break
+			}
+			if optionType := option.resolvedType(root); optionType != "" && matchesType(value, optionType) {
+				typed = append(typed, optionViolations)
+			}
+		}
+		if !matched && len(typed) == 1 {
+			violations = append(violations, typed[0]...)
+		} else if !matched {
+			violations = append(violations, fmt.Sprintf("%s: must %s", location, describeAnyOf(s.AnyOf)))
+		}
+	}
+
+	return violations
+}
+
+// resolvedType returns the type of the schema, following its reference
+func (s *Schema) resolvedType(root *Schema) string {
+	if s.Ref != "" {
+		if definition, ok := root.Definitions[strings.TrimPrefix(s.Ref, "#/definitions/")]; ok {
+			return definition.Type
+		}
+	}
+
+	return s.Type
+}
+
+// describeAnyOf describes what each of the anyOf options requires
+func describeAnyOf(options []*Schema) string {
+	descriptions := []string{}
+	for _, option := range options {
+		var description string
+		switch {
+		case option.Type != "":
+			description = "be a " + option.Type
+		case option.Ref != "":
+			description = "be a " + strings.TrimPrefix(option.Ref, "#/definitions/")
+		default:
+			description = "set " + strings.Join(option.Required, " and ")
+		}
+		if len(option.AnyOf) > 0 {
+			description += " and one of (" + describeAnyOf(option.AnyOf) + ")"
+		}
+		descriptions = append(descriptions, description)
+	}
+
+	return strings.Join(descriptions, " or ")
+}
+
+func matchesType(value interface{}, schemaType string) bool {
+	switch v := value.(type) {
+	case string:
+		return schemaType == "string"
+	case bool:
+		return schemaType == "boolean"
+	case int, int64:
+		return schemaType == "integer" || schemaType == "number"
+	case float64:
+		return schemaType == "number" || (schemaType == "integer" && v == float64(int64(v)))
+	case map[string]interface{}:
+		return schemaType == "object"
+	case []interface{}:
+		return schemaType == "array"
+	case nil:
+		return false
+	default:
+		return false
+	}
+}
+
+func describeType(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int64, float64:
+		return "number"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestValidateSchemaRequiredFields(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{name: "prebuilt", config: "prebuilt: image\nworkdir: /work"},
+		{name: "containerfile", config: "containerfile: Containerfile\nworkdir: /work"},
+		{name: "extends", config: "extends: base.yaml"},
+		{name: "compose", config: "compose:\n  file: compose.yaml\n  service: app"},
+		{name: "missing workdir", config: "prebuilt: image", wantErr: true},
+		{name: "missing image", config: "workdir: /work", wantErr: true},
+		{name: "unknown property", config: "prebuilt: image\nworkdir: /work\nimage: other", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateSchema([]byte(tc.config), "cade.yaml")
+			if tc.wantErr && err == nil {
+				t.Error("expected the config not to match the schema")
+			} else if !tc.wantErr && err != nil {
+				t.Errorf("expected the config to match the schema: %v", err)
+			}
+		})
+	}
+}
+
+func TestGenerateSchemaRefHasNoSiblings(t *testing.T) {
+	// draft-07 ignores the keywords next to $ref
+	schema := GenerateSchema()
+	if schema.Ref != "" && (len(schema.AnyOf) > 0 || len(schema.Required) > 0) {
+		t.Errorf("expected the required fields of the root schema not to be next to its $ref")
+	}
+}
+
+func TestRenderedConfigMatchesSchema(t *testing.T) {
+	for _, example := range []string{"cadeconfig.yaml", "cadeconfig.json", "cadeconfig.toml"} {
+		t.Run(example, func(t *testing.T) {
+			config, err := ParseWorkspaceConfig(filepath.Join("..", "..", "example", example), ParseOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error parsing the config: %v", err)
+			}
+
+			rendered, err := yaml.Marshal(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = validateSchema(rendered, "cade.yaml")
+			if err != nil {
+				t.Errorf("expected the rendered config to match the schema: %v\n%s", err, rendered)
+			}
+		})
+	}
+}
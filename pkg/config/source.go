@@ -0,0 +1,286 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// gitSourcePrefix is the prefix of config sources in a git repository, e.g.
+	// git::https://github.com/org/repo.git//path/to/cadeconfig.yaml?ref=main
+	gitSourcePrefix = "git::"
+
+	// TokenEnv is the environment variable containing a bearer token used to fetch remote configs
+	TokenEnv = "CADE_CONFIG_TOKEN"
+	// UsernameEnv is the environment variable containing the username used to fetch remote configs
+	UsernameEnv = "CADE_CONFIG_USERNAME"
+	// PasswordEnv is the environment variable containing the password used to fetch remote configs
+	PasswordEnv = "CADE_CONFIG_PASSWORD"
+	// HostsEnv is the environment variable containing the comma separated hosts
+	// the credentials from the environment are sent to when fetching remote configs
+	HostsEnv = "CADE_CONFIG_HOSTS"
+
+	fetchTimeout = 30 * time.Second
+)
+
+// gitSchemes are the URL schemes git repositories can be fetched with. Other
+// transports, such as ext::, can run commands on the host
+var gitSchemes = []string{"https", "ssh", "file"}
+
+// readSource reads the config from the provided source, which can be a local filepath,
+// an http(s) URL, a git:: source or - for stdin. Returns the config bytes, the name of the config
+// file within the source and an error if any occur during the process
+func readSource(source string) ([]byte, string, error) {
+	switch {
+	case source == "-":
+		configBytes, err := ioutil.ReadAll(os.Stdin)
+		return configBytes, "", err
+	case strings.HasPrefix(source, gitSourcePrefix):
+		return fetchWorkspaceConfigFromGit(strings.TrimPrefix(source, gitSourcePrefix))
+	case strings.HasPrefix(source, "https://"), strings.HasPrefix(source, "http://"):
+		parsed, err := url.Parse(source)
+		if err != nil {
+			return nil, "", fmt.Errorf("encountered an error parsing the URL: %w", err)
+		}
+
+		configBytes, err := fetchWorkspaceConfigFromURL(source)
+		return configBytes, path.Base(parsed.Path), err
+	default:
+		configBytes, err := os.ReadFile(source)
+		return configBytes, source, err
+	}
+}
+
+// verifySHA256 returns an error if the sha256 checksum of the config bytes does not match
+func verifySHA256(configBytes []byte, expected string) error {
+	sum := sha256.Sum256(configBytes)
+	actual := hex.EncodeToString(sum[:])
+	if !strings.EqualFold(actual, strings.TrimPrefix(expected, "sha256:")) {
+		return fmt.Errorf("the sha256 checksum of the config %s does not match the expected checksum %s", actual, expected)
+	}
+
+	return nil
+}
+
+// fetchWorkspaceConfigFromURL fetches the config from the URL. Responses are cached
+// on disk and revalidated with their ETag. If the URL can't be reached the cached
+// response is used so remote configs keep working offline
+func fetchWorkspaceConfigFromURL(source string) ([]byte, error) {
+	cacheBody, cacheETag, err := cachePaths(source)
+	if err != nil {
+		return nil, err
+	}
+
+	cached, cacheErr := os.ReadFile(cacheBody)
+	etag, _ := os.ReadFile(cacheETag)
+
+	req, err := http.NewRequest(http.MethodGet, source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error creating the request: %w", err)
+	}
+
+	err = setAuthorization(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheErr == nil && len(etag) > 0 {
+		req.Header.Set("If-None-Match", string(etag))
+	}
+
+	client := &http.Client{Timeout: fetchTimeout}
+	response, err := client.Do(req)
+	if err != nil {
+		if cacheErr == nil {
+			fmt.Println("Unable to fetch the config, using the cached copy:", err)
+			return cached, nil
+		}
+		return nil, fmt.Errorf("encountered an error fetching the data: %w", err)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotModified && cacheErr == nil {
+		return cached, nil
+	}
+
+	if response.StatusCode != http.StatusOK {
+		if (response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusForbidden) && req.Header.Get("Authorization") == "" && (os.Getenv(TokenEnv) != "" || os.Getenv(UsernameEnv) != "") {
+			return nil, fmt.Errorf("unexpected status %q fetching the data. The credentials from the environment are only sent over https to the hosts in %s", response.Status, HostsEnv)
+		}
+		return nil, fmt.Errorf("unexpected status %q fetching the data", response.Status)
+	}
+
+	bytes, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error reading the data: %w", err)
+	}
+
+	// failing to cache the config should not fail fetching it
+	if err := os.MkdirAll(filepath.Dir(cacheBody), 0700); err == nil {
+		os.WriteFile(cacheBody, bytes, 0600)
+		os.WriteFile(cacheETag, []byte(response.Header.Get("ETag")), 0600)
+	}
+
+	return bytes, nil
+}
+
+// setAuthorization sets the Authorization header on the request using the bearer token or
+// username and password from the environment or, if neither are set, the netrc file. The
+// credentials from the environment are only sent to the hosts in HostsEnv since any config
+// can extend a config on another host. No credentials are sent over plain http
+func setAuthorization(req *http.Request) error {
+	if req.URL.Scheme != "https" {
+		return nil
+	}
+
+	if credentialsHost(req.URL.Hostname()) {
+		if token := os.Getenv(TokenEnv); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+			return nil
+		}
+
+		if username := os.Getenv(UsernameEnv); username != "" {
+			req.SetBasicAuth(username, os.Getenv(PasswordEnv))
+			return nil
+		}
+	}
+
+	login, password, err := netrcCredentials(req.URL.Hostname())
+	if err != nil {
+		return err
+	}
+
+	if login != "" {
+		req.SetBasicAuth(login, password)
+	}
+
+	return nil
+}
+
+// credentialsHost returns whether or not the host is one of the hosts in HostsEnv
+func credentialsHost(host string) bool {
+	for _, allowed := range strings.Split(os.Getenv(HostsEnv), ",") {
+		if allowed = strings.TrimSpace(allowed); allowed != "" && strings.EqualFold(allowed, host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cachePaths returns the paths of the cached body and ETag for the URL
+func cachePaths(source string) (string, string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", "", fmt.Errorf("encountered an error getting the user cache directory: %w", err)
+	}
+
+	sum := sha256.Sum256([]byte(source))
+	base := filepath.Join(cacheDir, "cade", "configs", hex.EncodeToString(sum[:]))
+	return base + ".body", base + ".etag", nil
+}
+
+// fetchWorkspaceConfigFromGit reads the config from a git repository. The source is the
+// repository URL followed by // and the path of the config file in the repository. An
+// optional ref query parameter selects the branch, tag or commit, e.g.
+// https://github.com/org/repo.git//path/to/cadeconfig.yaml?ref=main
+func fetchWorkspaceConfigFromGit(source string) ([]byte, string, error) {
+	repo, subpath, ref, err := parseGitSource(source)
+	if err != nil {
+		return nil, "", err
+	}
+
+	dir, err := os.MkdirTemp("", "cade-config-")
+	if err != nil {
+		return nil, "", fmt.Errorf("encountered an error creating a temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// fetching the single ref works for branches, tags and commits alike
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"fetch", "--quiet", "--depth", "1", "--", repo, ref},
+		{"checkout", "--quiet", "FETCH_HEAD"},
+	} {
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		// redirects and submodules can't switch to another transport either
+		cmd.Env = append(os.Environ(), "GIT_ALLOW_PROTOCOL="+strings.Join(gitSchemes, ":"))
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return nil, "", fmt.Errorf("encountered an error running `git %s`: %w | out: %s", args[0], err, out)
+		}
+	}
+
+	configBytes, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(subpath)))
+	if err != nil {
+		return nil, "", fmt.Errorf("encountered an error reading `%s` from the git repository: %w", subpath, err)
+	}
+
+	return configBytes, subpath, nil
+}
+
+// parseGitSource splits a git source into the repository URL, the path of the config in it and the ref
+func parseGitSource(source string) (string, string, string, error) {
+	ref := "HEAD"
+	if base, query, ok := strings.Cut(source, "?"); ok {
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			return "", "", "", fmt.Errorf("encountered an error parsing the git source query: %w", err)
+		}
+		if values.Get("ref") != "" {
+			ref = values.Get("ref")
+		}
+		source = base
+	}
+
+	// skip the // of the URL scheme when looking for the subpath separator
+	start := 0
+	if i := strings.Index(source, "://"); i >= 0 {
+		start = i + len("://")
+	}
+
+	sep := strings.Index(source[start:], "//")
+	if sep < 0 {
+		return "", "", "", fmt.Errorf("the git source %q must include the path of the config, e.g. %s", source, "git::https://github.com/org/repo.git//cadeconfig.yaml")
+	}
+
+	repo := source[:start+sep]
+	err := validateGitSource(repo, ref)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return repo, source[start+sep+2:], ref, nil
+}
+
+// validateGitSource returns an error if the repository URL doesn't use one of the gitSchemes
+// or either it or the ref start with a -, which git would parse as an option
+func validateGitSource(repo string, ref string) error {
+	if strings.HasPrefix(repo, "-") || strings.HasPrefix(ref, "-") {
+		return fmt.Errorf("the repository %q and ref %q of the git source must not start with -", repo, ref)
+	}
+
+	scheme, _, ok := strings.Cut(repo, "://")
+	if !ok {
+		return fmt.Errorf("the repository of the git source %q must be a URL with one of the schemes: %s", repo, strings.Join(gitSchemes, ", "))
+	}
+
+	for _, allowed := range gitSchemes {
+		if strings.EqualFold(scheme, allowed) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unsupported scheme %q in the git source %q. must be one of: %s", scheme, repo, strings.Join(gitSchemes, ", "))
+}
@@ -0,0 +1,16 @@
+package config
+
+import (
+	"github.com/BurntSushi/toml"
+)
+
+// decodeTOML decodes a TOML document into a map
+func decodeTOML(data []byte) (map[string]interface{}, error) {
+	decoded := map[string]interface{}{}
+	err := toml.Unmarshal(data, &decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return decoded, nil
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// LocalOverlayName is the name of the untracked config that is merged
+// on top of a workspace config to apply per-user overrides
+const LocalOverlayName = "cade.local.yaml"
+
+// loadLocalOverlay reads the local overlay config next to the source. Nil if there is none.
+// For remote sources the overlay is read from the current directory
+func loadLocalOverlay(source string) (*WorkspaceConfig, error) {
+	overlayPath := LocalOverlayName
+	if source != "-" && !strings.HasPrefix(source, gitSourcePrefix) && !strings.Contains(source, "://") {
+		overlayPath = filepath.Join(filepath.Dir(source), LocalOverlayName)
+	}
+
+	configBytes, err := os.ReadFile(overlayPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("encountered an error reading the local overlay `%s`: %w", overlayPath, err)
+	}
+
+	overlay := &WorkspaceConfig{}
+	err = decode(configBytes, formatYAML, overlay)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error parsing the local overlay `%s`: %w", overlayPath, err)
+	}
+
+	return overlay, nil
+}
+
+// substituteVars replaces ${name} and ${name:-default} references in every string
+// of the config. Values are taken from set, then the environment and then the vars
+// of the config. Only variables declared in the vars are read from the environment so
+// a config can't send any other environment variable, such as a token, to a remote host.
+// A reference to an undeclared variable without a default is left as is so shell
+// variables such as ${HOME} in a healthcheck command still work. $$ is replaced with a literal $
+func substituteVars(config *WorkspaceConfig, set map[string]string) {
+	lookup := func(name string) (string, bool) {
+		if value, ok := set[name]; ok {
+			return value, true
+		}
+
+		value, declared := config.Vars[name]
+		if !declared {
+			return "", false
+		}
+
+		if envValue, ok := os.LookupEnv(name); ok {
+			return envValue, true
+		}
+
+		return value, true
+	}
+
+	value := reflect.ValueOf(config).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		// the vars are the values being substituted so they are left as is
+		if value.Type().Field(i).Name == "Vars" {
+			continue
+		}
+
+		substituteValue(value.Field(i), lookup)
+	}
+}
+
+// substituteValue substitutes variables in every string reachable from the value
+func substituteValue(value reflect.Value, lookup func(string) (string, bool)) {
+	switch value.Kind() {
+	case reflect.String:
+		if value.CanSet() {
+			value.SetString(expandVars(value.String(), lookup))
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).PkgPath == "" {
+				substituteValue(value.Field(i), lookup)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			substituteValue(value.Index(i), lookup)
+		}
+	case reflect.Ptr:
+		if !value.IsNil() {
+			substituteValue(value.Elem(), lookup)
+		}
+	case reflect.Map:
+		for _, key := range value.MapKeys() {
+			// map values aren't addressable so they are substituted in a copy
+			elem := reflect.New(value.Type().Elem()).Elem()
+			elem.Set(value.MapIndex(key))
+			substituteValue(elem, lookup)
+			value.SetMapIndex(key, elem)
+		}
+	}
+}
+
+// expandVars expands the variable references in the string
+func expandVars(s string, lookup func(string) (string, bool)) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 >= len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+
+		switch s[i+1] {
+		case '$':
+			sb.WriteByte('$')
+			i++
+		case '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				sb.WriteString(s[i:])
+				return sb.String()
+			}
+
+			name, fallback, hasDefault := strings.Cut(s[i+2:i+end], ":-")
+			if value, ok := lookup(name); ok {
+				sb.WriteString(value)
+			} else if hasDefault {
+				sb.WriteString(fallback)
+			} else {
+				sb.WriteString(s[i : i+end+1])
+			}
+			i += end
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestExpandVars(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		value, ok := map[string]string{"user": "me", "empty": ""}[name]
+		return value, ok
+	}
+
+	for _, tc := range []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "variable", value: "/home/${user}/src", want: "/home/me/src"},
+		{name: "empty variable", value: "a${empty}b", want: "ab"},
+		{name: "escape", value: "echo $${user} $$HOME", want: "echo ${user} $HOME"},
+		{name: "default", value: "${shell:-bash}", want: "bash"},
+		{name: "set variable ignores the default", value: "${user:-you}", want: "me"},
+		{name: "missing variable is left as is", value: "test -d ${HOME}/x", want: "test -d ${HOME}/x"},
+		{name: "unterminated reference", value: "a ${user", want: "a ${user"},
+		{name: "plain dollar", value: "$HOME and a trailing $", want: "$HOME and a trailing $"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := expandVars(tc.value, lookup)
+			if got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSubstituteVarsHealthcheck(t *testing.T) {
+	config := &WorkspaceConfig{
+		Workdir:     "/home/${user}",
+		Vars:        map[string]string{"user": "${me}"},
+		Healthcheck: &HealthcheckConfig{Command: "test -d ${HOME}/${user}"},
+	}
+
+	substituteVars(config, map[string]string{"user": "you"})
+
+	if config.Workdir != "/home/you" {
+		t.Errorf("expected the workdir /home/you, got %q", config.Workdir)
+	}
+	if config.Healthcheck.Command != "test -d ${HOME}/you" {
+		t.Errorf("expected the shell variable to be left as is, got %q", config.Healthcheck.Command)
+	}
+	if config.Vars["user"] != "${me}" {
+		t.Errorf("expected the vars not to be substituted, got %q", config.Vars["user"])
+	}
+}
@@ -1,5 +1,28 @@
 package containerutil
 
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/everettraven/cade/pkg/registry"
+)
+
+var (
+	// ErrNotFound is returned when the container or image
+	// an operation was performed on does not exist
+	ErrNotFound = errors.New("not found")
+
+	// ErrNotRunning is returned when an operation requires
+	// a running container but the container is not running
+	ErrNotRunning = errors.New("not running")
+
+	// ErrAlreadyExists is returned when the resource
+	// an operation would create already exists
+	ErrAlreadyExists = errors.New("already exists")
+)
+
 // TODO(everettraven): This is meant to be used later when multiple
 // Container runtimes are supported and a discovery feature is implemented
 // that will return a ContainerUtil interface object corresponding to the
@@ -12,35 +35,128 @@ type ContainerUtil interface {
 	// Returns an error if any occur during the process
 	Run(container Container, volumes []Volume, runArgs ...string) ([]byte, error)
 
-	// Build builds an image from the provided containerfile and tags it
-	// with the provided tag. Returns an error if any occur during the process
-	Build(containerfile string, tag string, context string) ([]byte, error)
+	// Build builds an image from the provided containerfile, tags it with
+	// the provided tag and applies the provided labels to it.
+	// Returns an error if any occur during the process
+	Build(containerfile string, tag string, context string, labels map[string]string) ([]byte, error)
 
 	// Exec will execute a command in the container with the provided name
 	// using the execOptions and the args provided. For example:
 	// docker exec {execOptions} {name} {args}
-	// Returns an error if any occur during the process
+	// Returns an error if any occur during the process. The error wraps
+	// ErrNotFound or ErrNotRunning if the container is missing or stopped
 	Exec(execOptions ExecOptions, name string, execArgs ...string) error
 
-	// ContainerList will return a list of containers
+	// Logs will write the output of the container with the provided name
+	// using the logsOptions. For example:
+	// docker logs {logsOptions} {name}
+	// Returns an error if any occur during the process. The error
+	// wraps ErrNotFound if the container does not exist
+	Logs(logsOptions LogsOptions, name string) error
+
+	// InspectContainer will return the container with the provided name
+	// and the volumes mounted in it. Returns an error if any occur during the
+	// process. The error wraps ErrNotFound if the container does not exist
+	InspectContainer(name string) (*Container, []Volume, error)
+
+	// ContainerList will return a list of all containers, including stopped ones
 	// Returns an error if any occur during the process
 	ContainerList() ([]Container, error)
 
-	// ImageList will return a list of images
+	// ImageList will return a list of images that have all of the provided labels.
 	// Returns an error if any occur during the process
-	ImageList() ([]Image, error)
+	ImageList(labels map[string]string) ([]Image, error)
 
-	// StopContainer will stop a running container.
+	// PullImage will pull the image with the provided reference from its registry,
+	// writing progress to stdout. If credentials are provided they are used to
+	// authenticate with the registry. Returns an error if any occur during the process
+	PullImage(ref string, credentials *registry.Credentials) error
+
+	// InspectImage will return the local image with the provided reference.
+	// Returns an error if any occur during the process. The error
+	// wraps ErrNotFound if the image does not exist locally
+	InspectImage(ref string) (*Image, error)
+
+	// RemoveImage will remove an image
+	// Returns an error if any occur during the process. The error
+	// wraps ErrNotFound if the image does not exist
+	RemoveImage(image Image) ([]byte, error)
+
+	// VolumeList will return a list of container volumes that have all of the provided labels.
+	// Returns an error if any occur during the process
+	VolumeList(labels map[string]string) ([]ContainerVolume, error)
+
+	// CreateVolume will create a container volume with the name and labels of the provided
+	// volume. Creating a volume that already exists is not an error.
 	// Returns an error if any occur during the process
+	CreateVolume(volume ContainerVolume) ([]byte, error)
+
+	// RemoveVolume will remove a container volume
+	// Returns an error if any occur during the process. The error
+	// wraps ErrNotFound if the volume does not exist
+	RemoveVolume(volume ContainerVolume) ([]byte, error)
+
+	// StopContainer will stop a running container.
+	// Returns an error if any occur during the process. The error wraps
+	// ErrNotFound or ErrNotRunning if the container is missing or stopped
 	StopContainer(container Container) ([]byte, error)
 
 	// RemoveContainer will remove a container
-	// Returns an error if any occur during the process
+	// Returns an error if any occur during the process. The error
+	// wraps ErrNotFound if the container does not exist
 	RemoveContainer(container Container) ([]byte, error)
 
+	// CreateNetwork will create the provided network
+	// Returns an error if any occur during the process. The error
+	// wraps ErrAlreadyExists if the network already exists
+	CreateNetwork(network Network) ([]byte, error)
+
+	// InspectNetwork will return the network with the provided name
+	// Returns an error if any occur during the process. The error
+	// wraps ErrNotFound if the network does not exist
+	InspectNetwork(name string) (*Network, error)
+
+	// RemoveNetwork will remove the network with the provided name
+	// Returns an error if any occur during the process. The error
+	// wraps ErrNotFound if the network does not exist
+	RemoveNetwork(name string) ([]byte, error)
+
+	// CopyToContainer will copy the host path of the volume
+	// into the running container at the mount path of the volume.
+	// Returns an error if any occur during the process. The error
+	// wraps ErrNotFound if the container does not exist
+	CopyToContainer(container Container, volume Volume) ([]byte, error)
+
+	// CopyArchiveFromContainer will write a tar archive of the path in the running
+	// container with the provided name to the writer. The archive has a single top
+	// level entry named after the base name of the path. For example:
+	// docker cp {name}:{path} -
+	// Returns an error if any occur during the process. The error wraps
+	// ErrNotFound if the container or the path does not exist
+	CopyArchiveFromContainer(name string, path string, archive io.Writer) error
+
+	// CopyArchiveToContainer will extract the tar archive read from the reader into
+	// the directory in the running container with the provided name. The permissions
+	// and owners of the files in the archive are kept. For example:
+	// docker cp --archive - {name}:{dir}
+	// Returns an error if any occur during the process. The error wraps
+	// ErrNotFound if the container or the directory does not exist
+	CopyArchiveToContainer(name string, dir string, archive io.Reader) error
+
+	// ComposeUp will create and start the containers of the compose project,
+	// building their images if needed, and wait for them to start.
+	// Returns an error if any occur during the process
+	ComposeUp(project ComposeProject, upArgs ...string) ([]byte, error)
+
+	// ComposeDown will stop and remove the containers and networks
+	// of the compose project with the provided name.
+	// Returns an error if any occur during the process
+	ComposeDown(name string) ([]byte, error)
+
 	// CopyToHost will copy files from within a container to
 	// the host directory. It uses a Volume definition to determine
-	// which directories to use for copy operations.
+	// which directories to use for copy operations. If the container
+	// has a numeric {uid}:{gid} user the copied files are owned by it.
 	// Returns an error if any occur during the process
 	CopyToHost(container Container, volume Volume) ([]byte, error)
 }
@@ -51,6 +167,42 @@ type Volume struct {
 	HostPath string `json:"host_path" yaml:"host_path"`
 	// The path in the container
 	MountPath string `json:"mount_path" yaml:"mount_path"`
+	// Whether or not the container can only read the volume
+	ReadOnly bool `json:"read_only,omitempty" yaml:"read_only,omitempty"`
+}
+
+// ContainerVolume represents a volume managed by the container runtime
+type ContainerVolume struct {
+	// The name of the volume
+	Name string
+	// The volume driver
+	Driver string
+	// Where the volume data is stored
+	Mountpoint string
+	// The labels on the volume
+	Labels map[string]string
+	// When the volume was created
+	CreatedAt time.Time
+}
+
+// Network represents a container network
+type Network struct {
+	// The name of the network
+	Name string
+	// The network driver
+	Driver string
+	// The labels on the network
+	Labels map[string]string
+	// The names of the containers attached to the network
+	Containers []string
+}
+
+// ComposeProject represents containers defined in compose files
+type ComposeProject struct {
+	// The name of the project
+	Name string
+	// The compose files that define the project. Later files override earlier ones
+	Files []string
 }
 
 // ExecOptions represent options that can be
@@ -61,6 +213,31 @@ type ExecOptions struct {
 	Tty         bool
 	User        string
 	Workdir     string
+	// Environment variables set for the command
+	Env map[string]string
+	// The streams of the command. The streams of
+	// the current process are used if they are nil
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// Resized is sent to when the size of the terminal the command is attached
+	// to changes so the size of the terminal of the command is updated
+	Resized <-chan struct{}
+}
+
+// LogsOptions represent options that can be
+// used to configure a Logs function call
+type LogsOptions struct {
+	// Whether or not to keep writing new output until the container stops
+	Follow bool
+	// Only write output since this timestamp or relative time such as 10m. All output if empty
+	Since string
+	// The number of lines to write from the end of the output. All lines if empty
+	Tail string
+	// The streams the container output is written to. The
+	// streams of the current process are used if they are nil
+	Stdout io.Writer
+	Stderr io.Writer
 }
 
 // Container represents a container
@@ -83,6 +260,67 @@ type Container struct {
 	Ports string
 	// Network the container should use
 	Network string
+	// Additional names the container can be reached by on its network
+	NetworkAliases []string
+	// Environment variables set in the container
+	Env map[string]string
+	// Ports published to the host, in the {host port}:{container port} format
+	Publish []string
+	// Additional hosts added to the container's hosts file, in the {host}:{ip} format
+	ExtraHosts []string
+	// DNS servers the container should use
+	DNS []string
+	// The user the container runs as, in the {user}[:{group}] format. Commands
+	// run with Exec use this user unless ExecOptions sets another one
+	User string
+	// The labels on the container
+	Labels map[string]string
+	// The names of the volumes mounted in the container
+	Mounts []string
+	// The names of the networks the container is attached to. Only set by ContainerList
+	Networks []string
+	// When the container was created. Unlike Created this is not relative to now
+	CreatedAt time.Time
+	// The command that checks the health of the container. Nil if it has none
+	Healthcheck *Healthcheck
+	// The health of the container. Nil if it has no health check
+	Health *Health
+}
+
+// Healthcheck represents the command that checks the health of a container
+type Healthcheck struct {
+	// The shell command run in the container. The container is healthy while it exits with 0
+	Command string
+	// The time between runs of the command. The runtime default is used if it is zero
+	Interval time.Duration
+	// The number of failed runs in a row after which the container
+	// is unhealthy. The runtime default is used if it is zero
+	Retries int
+	// The time the container has to start during which failed runs are
+	// not counted. The runtime default is used if it is zero
+	StartPeriod time.Duration
+}
+
+// Health represents the result of the health check of a container
+type Health struct {
+	// The health status of the container. One of starting, healthy or unhealthy
+	Status string
+	// The number of runs of the health check that failed in a row
+	FailingStreak int
+	// The most recent runs of the health check, oldest first
+	Probes []HealthProbe
+}
+
+// HealthProbe represents a run of the health check of a container
+type HealthProbe struct {
+	// When the run started
+	Start time.Time
+	// When the run ended
+	End time.Time
+	// The exit code of the command
+	ExitCode int
+	// The output of the command
+	Output string
 }
 
 // Image represents an Image
@@ -97,15 +335,29 @@ type Image struct {
 	Created string
 	// Size of the image
 	Size string
+	// The digest of the image in its registry, e.g. sha256:...
+	// Empty if the image has not been pushed or pulled
+	Digest string
+	// When the image was created, parsed from Created
+	CreatedAt time.Time
+	// The user containers run as by default. Empty if it is root
+	User string
+	// The labels on the image. Only set by InspectImage
+	Labels map[string]string
 }
 
 // NewContainerUtil is used to get an implementation of ContainerUtil
+// for the provided runtime. An empty runtime uses the default runtime.
+// Returns an error if the runtime is not supported
 // TODO(everettraven): Add discovery when multiple container runtimes
-// are supported. May be nice to also add a configuration setting where
-// a user can specify which runtime to be used (in the event they have
-// multiple runtimes installed)
-func NewContainerUtil() ContainerUtil {
+// are supported.
+func NewContainerUtil(runtime string) (ContainerUtil, error) {
+	switch runtime {
 	// For now since docker is the only supported runtime
 	// default to returning the docker utility
-	return NewDockerUtil()
+	case "", "docker":
+		return NewDockerUtil(), nil
+	default:
+		return nil, fmt.Errorf("unsupported container runtime %q. must be one of: docker", runtime)
+	}
 }
@@ -3,11 +3,28 @@ package containerutil
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/everettraven/cade/pkg/registry"
 )
 
+// sigwinch is the signal sent when the size of a terminal changes.
+// It is not defined by the syscall package on every platform
+const sigwinch = syscall.Signal(0x1c)
+
+// dockerTimeFormat is the format of timestamps in the output of Docker CLI list commands
+const dockerTimeFormat = "2006-01-02 15:04:05 -0700 MST"
+
 type Docker struct{}
 
 type dockerContainer struct {
@@ -19,12 +36,99 @@ type dockerContainer struct {
 	Status  string `json:"Status"`
 	State   string `json:"State"`
 	Ports   string `json:"Ports"`
+	// CreatedAt is the absolute creation time, unlike Created
+	CreatedAt string `json:"CreatedAt"`
+	Labels    string `json:"Labels"`
+	Mounts    string `json:"Mounts"`
+	Networks  string `json:"Networks"`
 }
 
 type dockerContainerList struct {
 	Containers []dockerContainer
 }
 
+type dockerImageInspect struct {
+	Id          string
+	RepoTags    []string
+	RepoDigests []string
+	Created     string
+	Size        int64
+	Config      struct {
+		User   string
+		Labels map[string]string
+	}
+}
+
+type dockerContainerInspect struct {
+	Id      string
+	Name    string
+	Created string
+	State   struct {
+		Status string
+		Health *struct {
+			Status        string
+			FailingStreak int
+			Log           []struct {
+				Start    string
+				End      string
+				ExitCode int
+				Output   string
+			}
+		}
+	}
+	Config struct {
+		Image       string
+		User        string
+		Labels      map[string]string
+		Healthcheck *struct {
+			Test        []string
+			Interval    time.Duration
+			Retries     int
+			StartPeriod time.Duration
+		}
+	}
+	HostConfig struct {
+		NetworkMode string
+		ExtraHosts  []string
+		Dns         []string
+	}
+	NetworkSettings struct {
+		Networks map[string]struct {
+			Aliases []string
+		}
+		Ports map[string][]dockerPortBinding
+	}
+	Mounts []struct {
+		Type        string
+		Name        string
+		Source      string
+		Destination string
+		RW          bool
+	}
+}
+
+type dockerPortBinding struct {
+	HostIp   string
+	HostPort string
+}
+
+type dockerNetwork struct {
+	Name       string
+	Driver     string
+	Labels     map[string]string
+	Containers map[string]struct {
+		Name string
+	}
+}
+
+type dockerVolume struct {
+	Name       string
+	Driver     string
+	Mountpoint string
+	Labels     map[string]string
+	CreatedAt  string
+}
+
 type dockerImage struct {
 	Containers   string
 	CreatedAt    string
@@ -62,13 +166,53 @@ func (d *Docker) Run(container Container, volumes []Volume, runArgs ...string) (
 	}
 
 	for _, volume := range volumes {
-		args = append(args, "-v", fmt.Sprintf("%s:%s", volume.HostPath, volume.MountPath))
+		spec := fmt.Sprintf("%s:%s", volume.HostPath, volume.MountPath)
+		if volume.ReadOnly {
+			spec += ":ro"
+		}
+		args = append(args, "-v", spec)
 	}
 
 	if container.Network != "" {
 		args = append(args, fmt.Sprintf("--network=%s", container.Network))
 	}
 
+	for _, alias := range container.NetworkAliases {
+		args = append(args, fmt.Sprintf("--network-alias=%s", alias))
+	}
+
+	for _, host := range container.ExtraHosts {
+		args = append(args, fmt.Sprintf("--add-host=%s", host))
+	}
+
+	for _, dns := range container.DNS {
+		args = append(args, fmt.Sprintf("--dns=%s", dns))
+	}
+
+	for _, publish := range container.Publish {
+		args = append(args, "-p", publish)
+	}
+
+	if container.User != "" {
+		args = append(args, "--user", container.User)
+	}
+
+	if healthcheck := container.Healthcheck; healthcheck != nil {
+		args = append(args, "--health-cmd", healthcheck.Command)
+		if healthcheck.Interval > 0 {
+			args = append(args, "--health-interval", healthcheck.Interval.String())
+		}
+		if healthcheck.Retries > 0 {
+			args = append(args, "--health-retries", strconv.Itoa(healthcheck.Retries))
+		}
+		if healthcheck.StartPeriod > 0 {
+			args = append(args, "--health-start-period", healthcheck.StartPeriod.String())
+		}
+	}
+
+	args = append(args, envArgs(container.Env)...)
+	args = append(args, labelArgs("--label", container.Labels)...)
+
 	args = append(args, container.Image)
 
 	args = append(args, runArgs...)
@@ -76,18 +220,21 @@ func (d *Docker) Run(container Container, volumes []Volume, runArgs ...string) (
 	return runDockerCmd(args...)
 }
 
-// Build builds an image from the provided containerfile and tags it
-// with the provided tag. Returns an error if any occur during the process
-func (d *Docker) Build(containerfile string, tag string, context string) ([]byte, error) {
+// Build builds an image from the provided containerfile, tags it with
+// the provided tag and applies the provided labels to it.
+// Returns an error if any occur during the process
+func (d *Docker) Build(containerfile string, tag string, context string, labels map[string]string) ([]byte, error) {
 	args := []string{
 		"build",
 		"-f",
 		containerfile,
 		"-t",
 		tag,
-		context,
 	}
 
+	args = append(args, labelArgs("--label", labels)...)
+	args = append(args, context)
+
 	return runDockerCmd(args...)
 }
 
@@ -120,27 +267,217 @@ func (d *Docker) Exec(execOptions ExecOptions, name string, execArgs ...string)
 		args = append(args, "-w", execOptions.Workdir)
 	}
 
+	args = append(args, envArgs(execOptions.Env)...)
+
 	args = append(args, name)
 	args = append(args, execArgs...)
 
 	cmd := exec.Command("docker", args...)
 
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
+	var stdin io.Reader = os.Stdin
+	var stdout, stderrOut io.Writer = os.Stdout, os.Stderr
+	if execOptions.Stdin != nil {
+		stdin = execOptions.Stdin
+	}
+	if execOptions.Stdout != nil {
+		stdout = execOptions.Stdout
+	}
+	if execOptions.Stderr != nil {
+		stderrOut = execOptions.Stderr
+	}
 
-	err := cmd.Run()
+	// the output of the command can be long so only its end is kept to classify errors of docker
+	stderr := &tailBuffer{max: errorOutputTail}
+	cmd.Stderr = io.MultiWriter(stderrOut, stderr)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+
+	err := cmd.Start()
+	if err != nil {
+		return dockerError(stderr.Bytes(), err)
+	}
+
+	// the docker CLI updates the size of the terminal of the command when it gets SIGWINCH
+	done := make(chan struct{})
+	defer close(done)
+	if execOptions.Resized != nil {
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				case <-execOptions.Resized:
+					cmd.Process.Signal(sigwinch)
+				}
+			}
+		}()
+	}
+
+	err = cmd.Wait()
+
+	// the exit code and output of the command are its own unless docker
+	// failed to run it, in which case docker exits with 125, 126 or 127
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		switch exitErr.ExitCode() {
+		case 125, 126, 127:
+			return dockerError(stderr.Bytes(), err)
+		}
+	}
 
 	return err
 }
 
-// ContainerList will return a list of containers
+// Logs will write the output of the container with the provided name
+// using the logsOptions. For example:
+// docker logs {logsOptions} {name}
+// Returns an error if any occur during the process
+func (d *Docker) Logs(logsOptions LogsOptions, name string) error {
+	args := []string{
+		"logs",
+	}
+
+	if logsOptions.Follow {
+		args = append(args, "--follow")
+	}
+
+	if logsOptions.Since != "" {
+		args = append(args, "--since", logsOptions.Since)
+	}
+
+	if logsOptions.Tail != "" {
+		args = append(args, "--tail", logsOptions.Tail)
+	}
+
+	args = append(args, name)
+
+	cmd := exec.Command("docker", args...)
+
+	var stdout, stderrOut io.Writer = os.Stdout, os.Stderr
+	if logsOptions.Stdout != nil {
+		stdout = logsOptions.Stdout
+	}
+	if logsOptions.Stderr != nil {
+		stderrOut = logsOptions.Stderr
+	}
+
+	stderr := &tailBuffer{max: errorOutputTail}
+	cmd.Stdout = stdout
+	cmd.Stderr = io.MultiWriter(stderrOut, stderr)
+
+	err := cmd.Run()
+
+	return dockerError(stderr.Bytes(), err)
+}
+
+// InspectContainer will return the container with the provided name
+// and the volumes mounted in it. Returns an error if any occur during the process
+func (d *Docker) InspectContainer(name string) (*Container, []Volume, error) {
+	args := []string{
+		"container",
+		"inspect",
+		name,
+	}
+
+	out, err := runDockerCmd(args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encountered an error using `docker` to inspect container %q: %w", name, err)
+	}
+
+	parsed := []dockerContainerInspect{}
+	err = json.Unmarshal(out, &parsed)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encountered an error parsing JSON from `docker container inspect` output: %w", err)
+	}
+
+	if len(parsed) == 0 {
+		return nil, nil, fmt.Errorf("%w: container %q", ErrNotFound, name)
+	}
+
+	c := parsed[0]
+	container := &Container{
+		Id:      c.Id,
+		Name:    strings.TrimPrefix(c.Name, "/"),
+		Image:   c.Config.Image,
+		Created: c.Created,
+		State:   c.State.Status,
+		User:    c.Config.User,
+		Labels:  c.Config.Labels,
+	}
+	container.CreatedAt, _ = time.Parse(time.RFC3339Nano, c.Created)
+
+	// the default network is not recorded so the container is recreated the same way
+	if c.HostConfig.NetworkMode != "default" && c.HostConfig.NetworkMode != "bridge" {
+		container.Network = c.HostConfig.NetworkMode
+	}
+
+	container.ExtraHosts = c.HostConfig.ExtraHosts
+	container.DNS = c.HostConfig.Dns
+
+	// docker adds the container name and short id as aliases itself
+	for _, alias := range c.NetworkSettings.Networks[container.Network].Aliases {
+		if alias != container.Name && !strings.HasPrefix(c.Id, alias) {
+			container.NetworkAliases = append(container.NetworkAliases, alias)
+		}
+	}
+
+	// only shell commands can be run again with Run. Health checks of other
+	// forms come from the image, which applies them to new containers itself
+	if h := c.Config.Healthcheck; h != nil && len(h.Test) == 2 && h.Test[0] == "CMD-SHELL" {
+		container.Healthcheck = &Healthcheck{
+			Command:     h.Test[1],
+			Interval:    h.Interval,
+			Retries:     h.Retries,
+			StartPeriod: h.StartPeriod,
+		}
+	}
+
+	if h := c.State.Health; h != nil {
+		container.Health = &Health{
+			Status:        h.Status,
+			FailingStreak: h.FailingStreak,
+		}
+		for _, probe := range h.Log {
+			start, _ := time.Parse(time.RFC3339Nano, probe.Start)
+			end, _ := time.Parse(time.RFC3339Nano, probe.End)
+			container.Health.Probes = append(container.Health.Probes, HealthProbe{
+				Start:    start,
+				End:      end,
+				ExitCode: probe.ExitCode,
+				Output:   probe.Output,
+			})
+		}
+	}
+
+	container.Ports = formatPorts(c.NetworkSettings.Ports)
+
+	volumes := []Volume{}
+	for _, mount := range c.Mounts {
+		hostPath := mount.Source
+		if mount.Type == "volume" {
+			hostPath = mount.Name
+			container.Mounts = append(container.Mounts, mount.Name)
+		}
+
+		volumes = append(volumes, Volume{
+			HostPath:  hostPath,
+			MountPath: mount.Destination,
+			ReadOnly:  !mount.RW,
+		})
+	}
+
+	return container, volumes, nil
+}
+
+// ContainerList will return a list of all containers, including stopped ones
 // Returns an error if any occur during the process
 func (d *Docker) ContainerList() ([]Container, error) {
 	containers := []Container{}
 	args := []string{
 		"container",
 		"list",
+		"--all",
+		"--no-trunc",
 		"--format",
 		"'{{json .}}'",
 	}
@@ -152,10 +489,7 @@ func (d *Docker) ContainerList() ([]Container, error) {
 
 	parsed := &dockerContainerList{}
 
-	out = bytes.TrimSpace(bytes.ReplaceAll(out, []byte("'"), []byte("")))
-	outList := bytes.Split(out, []byte("\n"))
-
-	for _, contain := range outList {
+	for _, contain := range splitJSONLines(out) {
 		container := &dockerContainer{}
 		err = json.Unmarshal(contain, container)
 		if err != nil {
@@ -167,31 +501,38 @@ func (d *Docker) ContainerList() ([]Container, error) {
 
 	for _, c := range parsed.Containers {
 		containers = append(containers, Container{
-			Id:      c.Id,
-			Name:    c.Name,
-			Created: c.Created,
-			Command: c.Command,
-			Image:   c.Image,
-			Ports:   c.Ports,
-			Status:  c.Status,
-			State:   c.State,
+			Id:        c.Id,
+			Name:      c.Name,
+			Created:   c.Created,
+			Command:   c.Command,
+			Image:     c.Image,
+			Ports:     c.Ports,
+			Status:    c.Status,
+			State:     c.State,
+			Labels:    parseLabels(c.Labels),
+			Mounts:    splitNonEmpty(c.Mounts, ","),
+			Networks:  splitNonEmpty(c.Networks, ","),
+			CreatedAt: parseDockerTime(c.CreatedAt),
 		})
 	}
 
 	return containers, nil
 }
 
-// ImageList will return a list of images
+// ImageList will return a list of images that have all of the provided labels.
 // Returns an error if any occur during the process
-func (d *Docker) ImageList() ([]Image, error) {
+func (d *Docker) ImageList(labels map[string]string) ([]Image, error) {
 	images := []Image{}
 	args := []string{
 		"image",
 		"list",
+		"--no-trunc",
 		"--format",
 		"'{{json .}}'",
 	}
 
+	args = append(args, labelArgs("--filter=label", labels)...)
+
 	out, err := runDockerCmd(args...)
 	if err != nil {
 		return nil, fmt.Errorf("encountered an error using `docker` to get list of images: %w", err)
@@ -199,9 +540,14 @@ func (d *Docker) ImageList() ([]Image, error) {
 
 	parsed := &dockerImageList{}
 
-	err = json.Unmarshal(out, parsed)
-	if err != nil {
-		return nil, fmt.Errorf("encountered an error parsing JSON from `docker image list` output: %w", err)
+	for _, img := range splitJSONLines(out) {
+		image := &dockerImage{}
+		err = json.Unmarshal(img, image)
+		if err != nil {
+			return nil, fmt.Errorf("encountered an error parsing JSON from `docker image list` output: %w | OUTPUT: %s", err, img)
+		}
+
+		parsed.Images = append(parsed.Images, *image)
 	}
 
 	for _, i := range parsed.Images {
@@ -211,12 +557,227 @@ func (d *Docker) ImageList() ([]Image, error) {
 			Id:         i.ID,
 			Created:    i.CreatedAt,
 			Size:       i.Size,
+			Digest:     strings.TrimPrefix(i.Digest, "<none>"),
+			CreatedAt:  parseDockerTime(i.CreatedAt),
 		})
 	}
 
 	return images, nil
 }
 
+// PullImage will pull the image with the provided reference from its registry,
+// writing progress to stdout. If credentials are provided they are used to
+// authenticate with the registry. Returns an error if any occur during the process
+func (d *Docker) PullImage(ref string, credentials *registry.Credentials) error {
+	args := []string{}
+
+	// the Docker CLI resolves the credentials from its own config itself
+	if credentials != nil && !credentials.FromDockerConfig {
+		configDir, err := dockerConfigOverride(credentials)
+		if configDir != "" {
+			defer os.RemoveAll(configDir)
+		}
+		if err != nil {
+			return err
+		}
+
+		args = append(args, "--config", configDir)
+	}
+
+	args = append(args, "pull", ref)
+
+	cmd := exec.Command("docker", args...)
+
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = io.MultiWriter(os.Stderr, stderr)
+	cmd.Stdout = os.Stdout
+
+	err := cmd.Run()
+
+	return dockerError(stderr.Bytes(), err)
+}
+
+// dockerConfigOverride returns a temporary Docker CLI config directory with the user's config and
+// the credentials added, since the Docker CLI only reads credentials from its config directory.
+// The contexts of the user are linked into it so the current context is still used. The directory
+// must be removed even if an error is returned. Returns an error if any occur during the process
+func dockerConfigOverride(credentials *registry.Credentials) (string, error) {
+	configDir, err := os.MkdirTemp("", "cade-docker-config-")
+	if err != nil {
+		return "", fmt.Errorf("encountered an error creating a temporary docker config directory: %w", err)
+	}
+
+	userConfigDir := registry.DockerConfigDir()
+
+	base := []byte{}
+	if userConfigDir != "" {
+		base, err = os.ReadFile(filepath.Join(userConfigDir, "config.json"))
+		if err != nil && !os.IsNotExist(err) {
+			return configDir, fmt.Errorf("encountered an error reading the docker config: %w", err)
+		}
+	}
+
+	config, err := credentials.DockerConfig(base)
+	if err != nil {
+		return configDir, fmt.Errorf("encountered an error generating the docker config: %w", err)
+	}
+
+	err = os.WriteFile(filepath.Join(configDir, "config.json"), config, 0600)
+	if err != nil {
+		return configDir, fmt.Errorf("encountered an error writing the docker config: %w", err)
+	}
+
+	if userConfigDir != "" {
+		contexts := filepath.Join(userConfigDir, "contexts")
+		if _, err := os.Stat(contexts); err == nil {
+			err = os.Symlink(contexts, filepath.Join(configDir, "contexts"))
+			if err != nil {
+				return configDir, fmt.Errorf("encountered an error linking the docker contexts: %w", err)
+			}
+		}
+	}
+
+	return configDir, nil
+}
+
+// InspectImage will return the local image with the provided reference.
+// Returns an error if any occur during the process
+func (d *Docker) InspectImage(ref string) (*Image, error) {
+	args := []string{
+		"image",
+		"inspect",
+		ref,
+	}
+
+	out, err := runDockerCmd(args...)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error using `docker` to inspect image %q: %w", ref, err)
+	}
+
+	parsed := []dockerImageInspect{}
+	err = json.Unmarshal(out, &parsed)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error parsing JSON from `docker image inspect` output: %w", err)
+	}
+
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("%w: image %q", ErrNotFound, ref)
+	}
+
+	i := parsed[0]
+	image := &Image{
+		Id:      i.Id,
+		Created: i.Created,
+		Size:    fmt.Sprintf("%dB", i.Size),
+		User:    i.Config.User,
+		Labels:  i.Config.Labels,
+	}
+	image.CreatedAt, _ = time.Parse(time.RFC3339Nano, i.Created)
+
+	if len(i.RepoTags) > 0 {
+		sep := strings.LastIndex(i.RepoTags[0], ":")
+		image.Repository, image.Tag = i.RepoTags[0][:sep], i.RepoTags[0][sep+1:]
+	}
+
+	image.Digest = repoDigest(i.RepoDigests, ref)
+
+	return image, nil
+}
+
+// RemoveImage will remove an image
+// Returns an error if any occur during the process
+func (d *Docker) RemoveImage(image Image) ([]byte, error) {
+	ref := image.Id
+	if image.Repository != "" && image.Repository != "<none>" && image.Tag != "" && image.Tag != "<none>" {
+		ref = fmt.Sprintf("%s:%s", image.Repository, image.Tag)
+	}
+
+	args := []string{
+		"image",
+		"rm",
+		ref,
+	}
+
+	return runDockerCmd(args...)
+}
+
+// VolumeList will return a list of container volumes that have all of the provided labels.
+// Returns an error if any occur during the process
+func (d *Docker) VolumeList(labels map[string]string) ([]ContainerVolume, error) {
+	volumes := []ContainerVolume{}
+	args := []string{
+		"volume",
+		"list",
+		"--quiet",
+	}
+
+	args = append(args, labelArgs("--filter=label", labels)...)
+
+	out, err := runDockerCmd(args...)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error using `docker` to get list of volumes: %w", err)
+	}
+
+	names := splitNonEmpty(string(out), "\n")
+	if len(names) == 0 {
+		return volumes, nil
+	}
+
+	out, err = runDockerCmd(append([]string{"volume", "inspect"}, names...)...)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error using `docker` to inspect volumes: %w", err)
+	}
+
+	parsed := []dockerVolume{}
+	err = json.Unmarshal(out, &parsed)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error parsing JSON from `docker volume inspect` output: %w", err)
+	}
+
+	for _, v := range parsed {
+		createdAt, _ := time.Parse(time.RFC3339, v.CreatedAt)
+		volumes = append(volumes, ContainerVolume{
+			Name:       v.Name,
+			Driver:     v.Driver,
+			Mountpoint: v.Mountpoint,
+			Labels:     v.Labels,
+			CreatedAt:  createdAt,
+		})
+	}
+
+	return volumes, nil
+}
+
+// CreateVolume will create a container volume
+// Returns an error if any occur during the process
+func (d *Docker) CreateVolume(volume ContainerVolume) ([]byte, error) {
+	args := []string{
+		"volume",
+		"create",
+	}
+
+	if volume.Driver != "" {
+		args = append(args, "--driver", volume.Driver)
+	}
+
+	args = append(args, labelArgs("--label", volume.Labels)...)
+	args = append(args, volume.Name)
+
+	return runDockerCmd(args...)
+}
+
+// RemoveVolume will remove a container volume
+// Returns an error if any occur during the process
+func (d *Docker) RemoveVolume(volume ContainerVolume) ([]byte, error) {
+	args := []string{
+		"volume",
+		"rm",
+		volume.Name,
+	}
+
+	return runDockerCmd(args...)
+}
+
 // StopContainer will stop a running container.
 // Returns an error if any occur during the process
 func (d *Docker) StopContainer(container Container) ([]byte, error) {
@@ -248,8 +809,112 @@ func (d *Docker) CreateContainer(container Container) ([]byte, error) {
 		"-it",
 		"--name",
 		container.Name,
-		container.Image,
-		"bash",
+	}
+
+	args = append(args, labelArgs("--label", container.Labels)...)
+	args = append(args, container.Image, "bash")
+
+	return runDockerCmd(args...)
+}
+
+// CreateNetwork will create the provided network
+// Returns an error if any occur during the process
+func (d *Docker) CreateNetwork(network Network) ([]byte, error) {
+	args := []string{
+		"network",
+		"create",
+	}
+
+	if network.Driver != "" {
+		args = append(args, "--driver", network.Driver)
+	}
+
+	args = append(args, labelArgs("--label", network.Labels)...)
+	args = append(args, network.Name)
+
+	return runDockerCmd(args...)
+}
+
+// InspectNetwork will return the network with the provided name
+// Returns an error if any occur during the process
+func (d *Docker) InspectNetwork(name string) (*Network, error) {
+	args := []string{
+		"network",
+		"inspect",
+		name,
+	}
+
+	out, err := runDockerCmd(args...)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error using `docker` to inspect network %q: %w", name, err)
+	}
+
+	parsed := []dockerNetwork{}
+	err = json.Unmarshal(out, &parsed)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error parsing JSON from `docker network inspect` output: %w", err)
+	}
+
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("%w: network %q", ErrNotFound, name)
+	}
+
+	n := parsed[0]
+	network := &Network{
+		Name:       n.Name,
+		Driver:     n.Driver,
+		Labels:     n.Labels,
+		Containers: []string{},
+	}
+
+	for _, c := range n.Containers {
+		network.Containers = append(network.Containers, c.Name)
+	}
+	sort.Strings(network.Containers)
+
+	return network, nil
+}
+
+// RemoveNetwork will remove the network with the provided name
+// Returns an error if any occur during the process
+func (d *Docker) RemoveNetwork(name string) ([]byte, error) {
+	args := []string{
+		"network",
+		"rm",
+		name,
+	}
+
+	return runDockerCmd(args...)
+}
+
+// ComposeUp will create and start the containers of the compose project
+// Returns an error if any occur during the process
+func (d *Docker) ComposeUp(project ComposeProject, upArgs ...string) ([]byte, error) {
+	args := []string{
+		"compose",
+		"--project-name",
+		project.Name,
+	}
+
+	for _, file := range project.Files {
+		args = append(args, "--file", file)
+	}
+
+	args = append(args, "up", "--detach", "--wait", "--remove-orphans")
+	args = append(args, upArgs...)
+
+	return runDockerCmd(args...)
+}
+
+// ComposeDown will stop and remove the containers and networks of the compose project
+// Returns an error if any occur during the process
+func (d *Docker) ComposeDown(name string) ([]byte, error) {
+	args := []string{
+		"compose",
+		"--project-name",
+		name,
+		"down",
+		"--remove-orphans",
 	}
 
 	return runDockerCmd(args...)
@@ -284,12 +949,278 @@ func (d *Docker) CopyToHost(container Container, volume Volume) ([]byte, error)
 		return out, fmt.Errorf("encountered an error removing the temporary container: %w", err)
 	}
 
+	err = chownUser(volume.HostPath, container.User)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error changing the owner of the copied files: %w", err)
+	}
+
 	return nil, nil
 }
 
+// chownUser changes the owner of the path and everything in it to the user if it is
+// in the numeric {uid}:{gid} format. Other users can't be resolved on the host so
+// the files are left owned by the user that copied them
+func chownUser(path string, user string) error {
+	uidString, gidString, ok := strings.Cut(user, ":")
+	if !ok {
+		return nil
+	}
+
+	uid, err := strconv.Atoi(uidString)
+	if err != nil {
+		return nil
+	}
+
+	gid, err := strconv.Atoi(gidString)
+	if err != nil {
+		return nil
+	}
+
+	return filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		return os.Lchown(path, uid, gid)
+	})
+}
+
+// CopyToContainer copies the host path of the volume into the container at the mount path of the volume.
+// Returns an error if any occur during the process
+func (d *Docker) CopyToContainer(container Container, volume Volume) ([]byte, error) {
+	args := []string{
+		"cp",
+		volume.HostPath,
+		fmt.Sprintf("%s:%s", container.Name, volume.MountPath),
+	}
+
+	return runDockerCmd(args...)
+}
+
+// CopyArchiveFromContainer writes a tar archive of the path in the container to the writer.
+// Returns an error if any occur during the process
+func (d *Docker) CopyArchiveFromContainer(name string, path string, archive io.Writer) error {
+	cmd := exec.Command("docker", "cp", fmt.Sprintf("%s:%s", name, path), "-")
+
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = io.MultiWriter(os.Stderr, stderr)
+	cmd.Stdout = archive
+
+	err := cmd.Run()
+
+	return dockerError(stderr.Bytes(), err)
+}
+
+// CopyArchiveToContainer extracts the tar archive from the reader into the directory in the container.
+// Returns an error if any occur during the process
+func (d *Docker) CopyArchiveToContainer(name string, dir string, archive io.Reader) error {
+	// --archive keeps the owners from the archive instead of making root the owner
+	cmd := exec.Command("docker", "cp", "--archive", "-", fmt.Sprintf("%s:%s", name, dir))
+
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = io.MultiWriter(os.Stderr, stderr)
+	cmd.Stdin = archive
+
+	err := cmd.Run()
+
+	return dockerError(stderr.Bytes(), err)
+}
+
+// repoDigest returns the digest from the repo digest matching the repository of the
+// reference, falling back to the first repo digest. Returns an empty string if there are none
+func repoDigest(repoDigests []string, ref string) string {
+	repository := ref
+	if name, _, ok := strings.Cut(ref, "@"); ok {
+		repository = name
+	} else if sep := strings.LastIndex(ref, ":"); sep > strings.LastIndex(ref, "/") {
+		repository = ref[:sep]
+	}
+
+	for _, repoDigest := range repoDigests {
+		name, digest, _ := strings.Cut(repoDigest, "@")
+		if name == repository {
+			return digest
+		}
+	}
+
+	if len(repoDigests) > 0 {
+		_, digest, _ := strings.Cut(repoDigests[0], "@")
+		return digest
+	}
+
+	return ""
+}
+
+// labelArgs converts labels into Docker CLI arguments of the form {flag}={key}={value}
+// sorted by key so the arguments are deterministic
+func labelArgs(flag string, labels map[string]string) []string {
+	args := []string{}
+	for key, value := range labels {
+		args = append(args, fmt.Sprintf("%s=%s=%s", flag, key, value))
+	}
+
+	sort.Strings(args)
+	return args
+}
+
+// envArgs converts environment variables into Docker CLI
+// arguments sorted by name so the arguments are deterministic
+func envArgs(env map[string]string) []string {
+	names := []string{}
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := []string{}
+	for _, name := range names {
+		args = append(args, "-e", fmt.Sprintf("%s=%s", name, env[name]))
+	}
+
+	return args
+}
+
+// formatPorts formats the ports of a container the way `docker container list` does, e.g.
+// 0.0.0.0:8080->80/tcp, 443/tcp. Ports that are not published are listed without a host address
+func formatPorts(ports map[string][]dockerPortBinding) string {
+	formatted := []string{}
+	for port, bindings := range ports {
+		if len(bindings) == 0 {
+			formatted = append(formatted, port)
+			continue
+		}
+
+		for _, binding := range bindings {
+			host := binding.HostIp
+			if strings.Contains(host, ":") {
+				host = "[" + host + "]"
+			}
+			formatted = append(formatted, fmt.Sprintf("%s:%s->%s", host, binding.HostPort, port))
+		}
+	}
+	sort.Strings(formatted)
+
+	return strings.Join(formatted, ", ")
+}
+
+// parseLabels parses labels from the `key=value,key=value` format used in Docker CLI output
+func parseLabels(labels string) map[string]string {
+	parsed := map[string]string{}
+	for _, label := range splitNonEmpty(labels, ",") {
+		key, value, _ := strings.Cut(label, "=")
+		parsed[key] = value
+	}
+
+	return parsed
+}
+
+// parseDockerTime parses a timestamp from Docker CLI output. Returns
+// the zero time if the timestamp is not in the expected format
+func parseDockerTime(timestamp string) time.Time {
+	parsed, err := time.Parse(dockerTimeFormat, timestamp)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return parsed
+}
+
+// splitJSONLines splits the output of a Docker CLI command run with
+// `--format '{{json .}}'` into one JSON document per line
+func splitJSONLines(out []byte) [][]byte {
+	out = bytes.TrimSpace(bytes.ReplaceAll(out, []byte("'"), []byte("")))
+	if len(out) == 0 {
+		return [][]byte{}
+	}
+
+	return bytes.Split(out, []byte("\n"))
+}
+
+func splitNonEmpty(s string, sep string) []string {
+	parts := []string{}
+	for _, part := range strings.Split(s, sep) {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	return parts
+}
+
 // runDockerCmd is a helper function to run the Docker CLI tool with the specified args.
 // Returns output of the command and an error if one occurred. This blocks until command is
 // complete and should not be used if you need realtime output/inputs.
 func runDockerCmd(args ...string) ([]byte, error) {
-	return exec.Command("docker", args...).CombinedOutput()
+	out, err := exec.Command("docker", args...).CombinedOutput()
+	return out, dockerError(out, err)
+}
+
+// dockerError wraps an error returned by the Docker CLI tool with ErrNotFound
+// or ErrNotRunning when the output of the command indicates that is the cause
+func dockerError(out []byte, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := strings.ToLower(string(out))
+	switch {
+	case strings.Contains(msg, "no such container"),
+		strings.Contains(msg, "no such image"),
+		strings.Contains(msg, "no such object"),
+		strings.Contains(msg, "no such volume"),
+		strings.Contains(msg, "could not find the file"),
+		strings.Contains(msg, "manifest unknown"),
+		strings.Contains(msg, "repository does not exist"),
+		strings.Contains(msg, "network") && strings.Contains(msg, "not found"):
+		return &dockerCmdError{sentinel: ErrNotFound, err: err}
+	case strings.Contains(msg, "is not running"):
+		return &dockerCmdError{sentinel: ErrNotRunning, err: err}
+	case strings.Contains(msg, "already exists"):
+		return &dockerCmdError{sentinel: ErrAlreadyExists, err: err}
+	default:
+		return err
+	}
+}
+
+// dockerCmdError is an error of a docker command that is one of the sentinel errors.
+// Both the sentinel error and the error of the command, such as its exit status, can be unwrapped
+type dockerCmdError struct {
+	sentinel error
+	err      error
+}
+
+func (e *dockerCmdError) Error() string {
+	return fmt.Sprintf("%s: %s", e.sentinel, e.err)
+}
+
+func (e *dockerCmdError) Is(target error) bool {
+	return target == e.sentinel
+}
+
+func (e *dockerCmdError) Unwrap() error {
+	return e.err
+}
+
+// errorOutputTail is the number of bytes at the end of the
+// output of long running commands kept to classify their errors
+const errorOutputTail = 4096
+
+// tailBuffer keeps the last max bytes written to it
+type tailBuffer struct {
+	max int
+	buf []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.max {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-t.max:]...)
+	}
+
+	return len(p), nil
+}
+
+// Bytes returns the bytes that are kept
+func (t *tailBuffer) Bytes() []byte {
+	return t.buf
 }
@@ -0,0 +1,166 @@
+package containerutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/everettraven/cade/pkg/registry"
+)
+
+// fakeDockerScript records the arguments it is run with and the
+// config.json of the --config directory, if any, in $FAKE_DOCKER_OUT
+const fakeDockerScript = `#!/bin/sh
+echo "$@" > "$FAKE_DOCKER_OUT/args"
+if [ "$1" = "--config" ]; then
+	cp "$2/config.json" "$FAKE_DOCKER_OUT/config.json"
+	[ -e "$2/contexts" ] && ls "$2/contexts" > "$FAKE_DOCKER_OUT/contexts"
+fi
+exit 0
+`
+
+func TestPullImageCredentials(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake docker is a shell script")
+	}
+
+	for _, tc := range []struct {
+		name         string
+		credentials  *registry.Credentials
+		wantOverride bool
+	}{
+		{name: "no credentials"},
+		{
+			name:        "credentials from the docker config",
+			credentials: &registry.Credentials{Registry: "registry.example.com", Username: "user", Password: "pass", FromDockerConfig: true},
+		},
+		{
+			name:         "credentials from another auth file",
+			credentials:  &registry.Credentials{Registry: "registry.example.com", Username: "user", Password: "pass"},
+			wantOverride: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			bin := filepath.Join(dir, "bin")
+			out := filepath.Join(dir, "out")
+			dockerConfig := filepath.Join(dir, "docker")
+			for _, d := range []string{bin, out, filepath.Join(dockerConfig, "contexts", "meta")} {
+				if err := os.MkdirAll(d, 0700); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := os.WriteFile(filepath.Join(bin, "docker"), []byte(fakeDockerScript), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dockerConfig, "config.json"), []byte(`{"currentContext":"colima","credsStore":"desktop"}`), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+			t.Setenv("FAKE_DOCKER_OUT", out)
+			t.Setenv("DOCKER_CONFIG", dockerConfig)
+
+			err := NewDockerUtil().PullImage("registry.example.com/team/app:v1", tc.credentials)
+			if err != nil {
+				t.Fatalf("unexpected error pulling the image: %v", err)
+			}
+
+			args, err := os.ReadFile(filepath.Join(out, "args"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			override := strings.HasPrefix(string(args), "--config ")
+			if override != tc.wantOverride {
+				t.Fatalf("expected a config override to be %t, docker was run with: %s", tc.wantOverride, args)
+			}
+			if !strings.HasSuffix(strings.TrimSpace(string(args)), "pull registry.example.com/team/app:v1") {
+				t.Errorf("expected docker to pull the image, it was run with: %s", args)
+			}
+
+			if !tc.wantOverride {
+				return
+			}
+
+			configBytes, err := os.ReadFile(filepath.Join(out, "config.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := struct {
+				CurrentContext string                       `json:"currentContext"`
+				CredsStore     string                       `json:"credsStore"`
+				Auths          map[string]map[string]string `json:"auths"`
+			}{}
+			if err := json.Unmarshal(configBytes, &config); err != nil {
+				t.Fatal(err)
+			}
+			if config.CurrentContext != "colima" || config.CredsStore != "" || config.Auths["registry.example.com"]["auth"] == "" {
+				t.Errorf("expected the user's config with the credentials added, got %s", configBytes)
+			}
+
+			if _, err := os.Stat(filepath.Join(out, "contexts")); err != nil {
+				t.Errorf("expected the contexts of the user to be available to docker: %v", err)
+			}
+
+			// the override with the credentials is removed after the pull
+			configDir := strings.Fields(string(args))[1]
+			if _, err := os.Stat(configDir); !os.IsNotExist(err) {
+				t.Errorf("expected the config override %s to be removed", configDir)
+			}
+		})
+	}
+}
+
+func TestExecErrors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake docker is a shell script")
+	}
+
+	for _, tc := range []struct {
+		name         string
+		stderr       string
+		exitCode     int
+		wantSentinel error
+	}{
+		{name: "success"},
+		{name: "command fails with docker like output", stderr: "file already exists", exitCode: 3},
+		{name: "docker fails", stderr: "Error response from daemon: No such container: ws", exitCode: 125, wantSentinel: ErrNotFound},
+		{name: "docker fails after long output", stderr: strings.Repeat("x", 3*errorOutputTail) + "container ws is not running", exitCode: 126, wantSentinel: ErrNotRunning},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bin := t.TempDir()
+			script := fmt.Sprintf("#!/bin/sh\nprintf '%%s' '%s' >&2\nexit %d\n", tc.stderr, tc.exitCode)
+			if err := os.WriteFile(filepath.Join(bin, "docker"), []byte(script), 0755); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+			err := NewDockerUtil().Exec(ExecOptions{Stdin: strings.NewReader(""), Stdout: io.Discard, Stderr: io.Discard}, "ws", "true")
+			if tc.exitCode == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) || exitErr.ExitCode() != tc.exitCode {
+				t.Errorf("expected the exit code %d to be kept, got %v", tc.exitCode, err)
+			}
+
+			for _, sentinel := range []error{ErrNotFound, ErrNotRunning, ErrAlreadyExists} {
+				if errors.Is(err, sentinel) != (sentinel == tc.wantSentinel) {
+					t.Errorf("expected errors.Is(%v) to be %t, got the error %v", sentinel, sentinel == tc.wantSentinel, err)
+				}
+			}
+		})
+	}
+}
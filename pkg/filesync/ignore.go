@@ -0,0 +1,53 @@
+package filesync
+
+import (
+	"path"
+	"strings"
+)
+
+// Ignore are the patterns of the files that are not synced. Patterns without a slash
+// match the name of a file or any of the directories it is in, like .git or *.o. Patterns
+// with one match the path relative to the synced directory, or of a directory it is in,
+// like build/out. Ignored files are left alone on both sides
+type Ignore []string
+
+// Match returns whether or not the file at the slash separated relative path is ignored
+func (i Ignore) Match(rel string) bool {
+	elements := strings.Split(rel, "/")
+
+	for _, pattern := range i {
+		if pattern = strings.Trim(pattern, "/"); pattern == "" {
+			continue
+		}
+
+		if !strings.Contains(pattern, "/") {
+			for _, element := range elements {
+				if matched, _ := path.Match(pattern, element); matched {
+					return true
+				}
+			}
+			continue
+		}
+
+		for n := 1; n <= len(elements); n++ {
+			if matched, _ := path.Match(pattern, strings.Join(elements[:n], "/")); matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// names returns the patterns without a slash. Directories matching them
+// are not descended into when listing the files in a container
+func (i Ignore) names() []string {
+	names := []string{}
+	for _, pattern := range i {
+		if pattern = strings.Trim(pattern, "/"); pattern != "" && !strings.Contains(pattern, "/") {
+			names = append(names, pattern)
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,129 @@
+package filesync
+
+import (
+	"sort"
+	"strings"
+)
+
+// action is what is done to sync a file
+type action int
+
+const (
+	// push copies the host version of the file to the container
+	push action = iota
+	// pull copies the container version of the file to the host
+	pull
+	// deleteHost removes the file from the host
+	deleteHost
+	// deleteContainer removes the file from the container
+	deleteContainer
+	// conflict keeps the host version of a file that changed on both sides and saves
+	// the container version next to it on the host so the changes can be merged
+	conflict
+)
+
+// change is an action on the file at the path
+type change struct {
+	path   string
+	action action
+}
+
+// plan compares the snapshots of the host and the container with the snapshot from the last
+// sync and returns the changes that bring both sides back in sync, ordered by path so
+// directories come before what is in them. A file that changed on one side is copied to the
+// other and a file that was removed on one side is removed from the other unless it changed
+// there. Without a base snapshot the initial reconciliation copies files that are only on one
+// side to the other and keeps the host version of files that differ. They are only conflicts
+// if the container version is newer since the container may then have changes the host doesn't
+func plan(base Snapshot, host Snapshot, container Snapshot) []change {
+	paths := map[string]bool{}
+	for _, snapshot := range []Snapshot{base, host, container} {
+		for path := range snapshot {
+			paths[path] = true
+		}
+	}
+
+	changes := []change{}
+	for path := range paths {
+		h, onHost := host[path]
+		c, inContainer := container[path]
+		if onHost == inContainer && (!onHost || h.Equal(c)) {
+			continue
+		}
+
+		if base == nil {
+			switch {
+			case !inContainer:
+				changes = append(changes, change{path, push})
+			case !onHost:
+				changes = append(changes, change{path, pull})
+			case h.Dir != c.Dir || (!h.Dir && c.ModTime > h.ModTime):
+				changes = append(changes, change{path, conflict})
+			default:
+				changes = append(changes, change{path, push})
+			}
+			continue
+		}
+
+		b, inBase := base[path]
+		hostChanged := onHost != inBase || (onHost && !h.Equal(b))
+		containerChanged := inContainer != inBase || (inContainer && !c.Equal(b))
+
+		switch {
+		case hostChanged && !containerChanged && onHost:
+			changes = append(changes, change{path, push})
+		case hostChanged && !containerChanged:
+			changes = append(changes, change{path, deleteContainer})
+		case containerChanged && !hostChanged && inContainer:
+			changes = append(changes, change{path, pull})
+		case containerChanged && !hostChanged:
+			changes = append(changes, change{path, deleteHost})
+		// a file that was changed on one side and removed on the other is kept
+		case !onHost:
+			changes = append(changes, change{path, pull})
+		case !inContainer:
+			changes = append(changes, change{path, push})
+		default:
+			changes = append(changes, change{path, conflict})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].path < changes[j].path
+	})
+
+	return keepCopiedParents(changes)
+}
+
+// keepCopiedParents drops the removal of directories that a file is copied into since
+// removing them would remove the file. What else was removed from them is still removed
+func keepCopiedParents(changes []change) []change {
+	copied := map[string]bool{}
+	for _, change := range changes {
+		if change.action == push || change.action == pull || change.action == conflict {
+			for dir := parent(change.path); dir != ""; dir = parent(dir) {
+				copied[dir] = true
+			}
+		}
+	}
+
+	kept := []change{}
+	for _, change := range changes {
+		if (change.action == deleteHost || change.action == deleteContainer) && copied[change.path] {
+			continue
+		}
+		kept = append(kept, change)
+	}
+
+	return kept
+}
+
+// parent returns the slash separated path of the directory the path is in. Empty for top level paths
+func parent(path string) string {
+	i := strings.LastIndex(path, "/")
+	if i < 0 {
+		return ""
+	}
+
+	return path[:i]
+}
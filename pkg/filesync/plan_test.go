@@ -0,0 +1,181 @@
+package filesync
+
+import (
+	"reflect"
+	"testing"
+)
+
+var (
+	dir      = Entry{Dir: true, Mode: 0755}
+	original = Entry{Mode: 0644, Size: 1, ModTime: 100}
+	older    = Entry{Mode: 0644, Size: 2, ModTime: 50}
+	newer    = Entry{Mode: 0644, Size: 3, ModTime: 200}
+)
+
+func TestPlan(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		base      Snapshot
+		host      Snapshot
+		container Snapshot
+		want      []change
+	}{
+		{
+			name:      "nothing changed",
+			base:      Snapshot{"a": original},
+			host:      Snapshot{"a": original},
+			container: Snapshot{"a": original},
+			want:      []change{},
+		},
+		{
+			name:      "directories with different modification times are the same",
+			base:      Snapshot{"d": dir},
+			host:      Snapshot{"d": {Dir: true, Mode: 0700, ModTime: 1}},
+			container: Snapshot{"d": {Dir: true, Mode: 0755, ModTime: 2}},
+			want:      []change{},
+		},
+		{
+			name:      "initial sync copies files only on one side",
+			host:      Snapshot{"a": original},
+			container: Snapshot{"b": original},
+			want:      []change{{"a", push}, {"b", pull}},
+		},
+		{
+			name:      "initial sync keeps the host version of an older container file",
+			host:      Snapshot{"a": newer},
+			container: Snapshot{"a": older},
+			want:      []change{{"a", push}},
+		},
+		{
+			name:      "initial sync conflicts on a newer container file",
+			host:      Snapshot{"a": older},
+			container: Snapshot{"a": newer},
+			want:      []change{{"a", conflict}},
+		},
+		{
+			name:      "initial sync conflicts on a file and a directory",
+			host:      Snapshot{"a": original},
+			container: Snapshot{"a": dir},
+			want:      []change{{"a", conflict}},
+		},
+		{
+			name:      "an empty base is not an initial sync",
+			base:      Snapshot{},
+			host:      Snapshot{},
+			container: Snapshot{"a": original},
+			want:      []change{{"a", pull}},
+		},
+		{
+			name:      "changed on the host",
+			base:      Snapshot{"a": original},
+			host:      Snapshot{"a": newer},
+			container: Snapshot{"a": original},
+			want:      []change{{"a", push}},
+		},
+		{
+			name:      "changed in the container",
+			base:      Snapshot{"a": original},
+			host:      Snapshot{"a": original},
+			container: Snapshot{"a": newer},
+			want:      []change{{"a", pull}},
+		},
+		{
+			name:      "added on the host",
+			base:      Snapshot{},
+			host:      Snapshot{"a": original},
+			container: Snapshot{},
+			want:      []change{{"a", push}},
+		},
+		{
+			name:      "removed from the host",
+			base:      Snapshot{"a": original},
+			host:      Snapshot{},
+			container: Snapshot{"a": original},
+			want:      []change{{"a", deleteContainer}},
+		},
+		{
+			name:      "removed from the container",
+			base:      Snapshot{"a": original},
+			host:      Snapshot{"a": original},
+			container: Snapshot{},
+			want:      []change{{"a", deleteHost}},
+		},
+		{
+			name:      "removed from both",
+			base:      Snapshot{"a": original},
+			host:      Snapshot{},
+			container: Snapshot{},
+			want:      []change{},
+		},
+		{
+			name:      "changed the same way on both",
+			base:      Snapshot{"a": original},
+			host:      Snapshot{"a": newer},
+			container: Snapshot{"a": newer},
+			want:      []change{},
+		},
+		{
+			name:      "changed differently on both",
+			base:      Snapshot{"a": original},
+			host:      Snapshot{"a": older},
+			container: Snapshot{"a": newer},
+			want:      []change{{"a", conflict}},
+		},
+		{
+			name:      "added differently on both",
+			base:      Snapshot{},
+			host:      Snapshot{"a": older},
+			container: Snapshot{"a": newer},
+			want:      []change{{"a", conflict}},
+		},
+		{
+			name:      "changed on the host and removed from the container",
+			base:      Snapshot{"a": original},
+			host:      Snapshot{"a": newer},
+			container: Snapshot{},
+			want:      []change{{"a", push}},
+		},
+		{
+			name:      "changed in the container and removed from the host",
+			base:      Snapshot{"a": original},
+			host:      Snapshot{},
+			container: Snapshot{"a": newer},
+			want:      []change{{"a", pull}},
+		},
+		{
+			name:      "directories come before what is in them",
+			base:      Snapshot{},
+			host:      Snapshot{"d/a": original, "d": dir},
+			container: Snapshot{},
+			want:      []change{{"d", push}, {"d/a", push}},
+		},
+		{
+			name:      "removed directory is kept for a file copied into it",
+			base:      Snapshot{"d": dir, "d/a": original, "d/b": original},
+			host:      Snapshot{},
+			container: Snapshot{"d": dir, "d/a": newer, "d/b": original},
+			want:      []change{{"d/a", pull}, {"d/b", deleteContainer}},
+		},
+		{
+			name:      "removed nested directories are kept for a file copied into them",
+			base:      Snapshot{"d": dir, "d/e": dir, "d/e/a": original},
+			host:      Snapshot{"d": dir, "d/e": dir, "d/e/a": newer},
+			container: Snapshot{},
+			want:      []change{{"d/e/a", push}},
+		},
+		{
+			name:      "removed directory without copied files is removed",
+			base:      Snapshot{"d": dir, "d/a": original, "e": dir},
+			host:      Snapshot{"e": dir},
+			container: Snapshot{"d": dir, "d/a": original, "e": dir},
+			want:      []change{{"d", deleteContainer}, {"d/a", deleteContainer}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := plan(tc.base, tc.host, tc.container)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected the changes %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package filesync
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Entry is the state of a file in a synced directory. Files are synced with their
+// permissions and modification times so an unchanged file has the same entry on both sides
+type Entry struct {
+	Dir  bool `json:"dir,omitempty"`
+	Link bool `json:"link,omitempty"`
+	// The permission bits of the file
+	Mode os.FileMode `json:"mode,omitempty"`
+	Size int64       `json:"size,omitempty"`
+	// The modification time of the file in seconds since the Unix epoch
+	ModTime int64 `json:"mod_time,omitempty"`
+}
+
+// Equal returns whether or not the entries are the same version of a file. The permissions
+// and modification times of directories change with what is in them so they are not compared
+// and neither are those of symbolic links since they can't always be set
+func (e Entry) Equal(other Entry) bool {
+	switch {
+	case e.Dir || other.Dir:
+		return e.Dir == other.Dir
+	case e.Link || other.Link:
+		return e.Link == other.Link && e.Size == other.Size
+	default:
+		return e == other
+	}
+}
+
+// Snapshot is the state of the files in a synced directory, keyed by their
+// slash separated paths relative to it
+type Snapshot map[string]Entry
+
+// ScanHost returns the snapshot of the directory on the host, leaving out ignored files.
+// Returns an error if any occur during the process
+func ScanHost(dir string, ignore Ignore) (Snapshot, error) {
+	snapshot := Snapshot{}
+
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) && file != dir {
+			// removed while scanning
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if file == dir {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if ignore.Match(rel) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// sockets, devices and fifos can't be synced
+		if !info.IsDir() && !info.Mode().IsRegular() && info.Mode()&os.ModeSymlink == 0 {
+			return nil
+		}
+
+		snapshot[rel] = newEntry(info.IsDir(), info.Mode()&os.ModeSymlink != 0, info.Mode().Perm(), info.Size(), info.ModTime().Unix())
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error scanning `%s`: %w", dir, err)
+	}
+
+	return snapshot, nil
+}
+
+// newEntry returns the entry of a file with only the fields that Equal compares for its type
+// set, so snapshots of the same files on the host and in a container are identical
+func newEntry(dir bool, link bool, mode os.FileMode, size int64, modTime int64) Entry {
+	switch {
+	case dir:
+		return Entry{Dir: true}
+	case link:
+		return Entry{Link: true, Size: size}
+	default:
+		return Entry{Mode: mode, Size: size, ModTime: modTime}
+	}
+}
+
+// parseContainerScan parses the output of containerScanScript into a snapshot,
+// leaving out ignored files. Returns an error if the output can't be parsed
+func parseContainerScan(out string, ignore Ignore) (Snapshot, error) {
+	snapshot := Snapshot{}
+
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+
+		// {modification time} {size} {raw mode in hex} {path}
+		fields := strings.SplitN(line, " ", 4)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected line %q in the file list of the container", line)
+		}
+
+		modTime, timeErr := strconv.ParseInt(fields[0], 10, 64)
+		size, sizeErr := strconv.ParseInt(fields[1], 10, 64)
+		rawMode, modeErr := strconv.ParseUint(fields[2], 16, 32)
+		if timeErr != nil || sizeErr != nil || modeErr != nil {
+			return nil, fmt.Errorf("unexpected line %q in the file list of the container", line)
+		}
+
+		rel := strings.TrimPrefix(fields[3], "./")
+		if rel == "." || ignore.Match(rel) {
+			continue
+		}
+
+		fileType := rawMode & 0170000
+		if fileType != 0040000 && fileType != 0100000 && fileType != 0120000 {
+			continue
+		}
+
+		snapshot[rel] = newEntry(fileType == 0040000, fileType == 0120000, os.FileMode(rawMode&0777), size, modTime)
+	}
+
+	return snapshot, nil
+}
+
+// LoadSnapshot reads the snapshot saved at the path. Returns a nil
+// snapshot if there is none and an error if any occur during the process
+func LoadSnapshot(path string) (Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("encountered an error reading the sync state `%s`: %w", path, err)
+	}
+
+	snapshot := Snapshot{}
+	err = json.Unmarshal(data, &snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error parsing the sync state `%s`: %w", path, err)
+	}
+
+	return snapshot, nil
+}
+
+// Save writes the snapshot to the path. Returns an error if any occur during the process
+func (s Snapshot) Save(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("encountered an error encoding the sync state: %w", err)
+	}
+
+	return writeFileAtomic(path, data)
+}
@@ -0,0 +1,74 @@
+package filesync
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Status is the state of the process that syncs a workspace,
+// which it saves after every sync so it can be reported
+type Status struct {
+	// The process ID of the process that syncs
+	PID int `json:"pid"`
+	// When syncing started
+	Started time.Time `json:"started"`
+	// When the directories were last synced without an error
+	LastSync time.Time `json:"last_sync,omitempty"`
+	// The error of the last sync. Empty if it succeeded
+	LastError string `json:"last_error,omitempty"`
+	// The number of files copied to the container since syncing started
+	Pushed int `json:"pushed"`
+	// The number of files copied to the host since syncing started
+	Pulled int `json:"pulled"`
+	// The number of files removed from either side since syncing started
+	Deleted int `json:"deleted"`
+	// The files that changed on both sides since syncing started
+	Conflicts []Conflict `json:"conflicts,omitempty"`
+}
+
+// LoadStatus reads the status saved at the path. Returns a nil status
+// if there is none and an error if any occur during the process
+func LoadStatus(path string) (*Status, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("encountered an error reading the sync status `%s`: %w", path, err)
+	}
+
+	status := &Status{}
+	err = json.Unmarshal(data, status)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error parsing the sync status `%s`: %w", path, err)
+	}
+
+	return status, nil
+}
+
+// Save writes the status to the path. Returns an error if any occur during the process
+func (s *Status) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encountered an error encoding the sync status: %w", err)
+	}
+
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic replaces the file at the path with the data so it is never read half written
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	err := os.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return fmt.Errorf("encountered an error writing `%s`: %w", tmp, err)
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return fmt.Errorf("encountered an error writing `%s`: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,337 @@
+package filesync
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/everettraven/cade/pkg/archive"
+	"github.com/everettraven/cade/pkg/containerutil"
+)
+
+// containerScanScript lists the files in the directory passed as the first argument. The
+// remaining arguments are the find expression that prunes ignored directories. Files
+// removed while listing make stat fail, so only failing to enter the directory is an error
+const containerScanScript = `cd "$1" || exit 1
+shift
+find . "$@" -exec stat -c '%Y %s %f %n' {} + 2>/dev/null
+exit 0
+`
+
+// conflictTimeFormat is the format of the time in the names of conflict copies
+const conflictTimeFormat = "20060102T150405Z"
+
+// Conflict is a file that changed on both the host and in the container
+type Conflict struct {
+	// The slash separated path of the file relative to the synced directory
+	Path string `json:"path"`
+	// The path relative to the synced directory that the container version was saved to
+	Copy string `json:"copy"`
+	// When the conflict was found
+	Time time.Time `json:"time"`
+}
+
+// Result is what a sync changed
+type Result struct {
+	// The paths copied from the host to the container
+	Pushed []string
+	// The paths copied from the container to the host
+	Pulled []string
+	// The paths removed from either side
+	Deleted []string
+	// The files that changed on both sides
+	Conflicts []Conflict
+}
+
+// Syncer syncs a directory on the host with a directory in a running container. Each sync
+// copies the changes since the previous one in both directions, using Base to tell which
+// side changed. Files are copied as tar streams so they keep their permissions and
+// modification times, which lets unchanged files be recognized on both sides
+type Syncer struct {
+	// The ContainerUtil used to access the container
+	ContainerUtil containerutil.ContainerUtil
+	// The name of the container
+	Container string
+	// The synced directory on the host
+	HostDir string
+	// The synced directory in the container
+	ContainerDir string
+	// The files that are not synced
+	Ignore Ignore
+	// The owner of the files copied into the container
+	Owner archive.Owner
+	// The files as they were after the previous sync. Nil before the
+	// first sync, which reconciles the directories instead
+	Base Snapshot
+}
+
+// Sync syncs the directories once and updates Base. Returns what changed
+// and an error if any occur during the process
+func (s *Syncer) Sync() (*Result, error) {
+	host, err := ScanHost(s.HostDir, s.Ignore)
+	if err != nil {
+		return nil, err
+	}
+
+	container, err := s.scanContainer()
+	if err != nil {
+		return nil, err
+	}
+
+	// an empty side is much more likely to be a directory that is missing, such as
+	// a volume that was recreated, than everything having been deleted on purpose
+	if len(s.Base) > 0 && (len(host) == 0) != (len(container) == 0) {
+		return nil, fmt.Errorf("refusing to sync because one of the directories is empty, which would remove every file from the other. Start syncing with --reset to reconcile the directories instead")
+	}
+
+	changes := plan(s.Base, host, container)
+	result := &Result{}
+
+	// the container version of a conflicted directory is copied with everything in it
+	conflictedDirs := map[string]bool{}
+	for _, change := range changes {
+		if change.action == conflict && container[change.path].Dir {
+			conflictedDirs[change.path] = true
+		}
+	}
+
+	hostDeletes, containerDeletes := []string{}, []string{}
+	pushes, pulls, conflicts := []string{}, []string{}, []string{}
+	for _, change := range changes {
+		if inConflictedDir(change.path, conflictedDirs) {
+			continue
+		}
+
+		switch change.action {
+		case deleteHost:
+			hostDeletes = append(hostDeletes, change.path)
+		case deleteContainer:
+			containerDeletes = append(containerDeletes, change.path)
+		case push:
+			pushes = append(pushes, change.path)
+			// a file can't be copied over a directory or the other way around
+			if c, ok := container[change.path]; ok && c.Dir != host[change.path].Dir {
+				containerDeletes = append(containerDeletes, change.path)
+			}
+		case pull:
+			pulls = append(pulls, change.path)
+			if h, ok := host[change.path]; ok && h.Dir != container[change.path].Dir {
+				hostDeletes = append(hostDeletes, change.path)
+			}
+		case conflict:
+			conflicts = append(conflicts, change.path)
+			if h, c := host[change.path], container[change.path]; h.Dir != c.Dir {
+				containerDeletes = append(containerDeletes, change.path)
+			}
+		}
+	}
+
+	// the container versions of conflicts are saved before anything is removed
+	now := time.Now().UTC()
+	for _, rel := range conflicts {
+		copyPath := fmt.Sprintf("%s.conflict-%s", rel, now.Format(conflictTimeFormat))
+		err = s.pull(rel, container[rel], path.Base(copyPath))
+		if err != nil {
+			return nil, err
+		}
+		result.Conflicts = append(result.Conflicts, Conflict{Path: rel, Copy: copyPath, Time: now})
+	}
+
+	for _, rel := range hostDeletes {
+		err = archive.CheckParents(s.HostDir, filepath.FromSlash(rel))
+		if err != nil {
+			return nil, err
+		}
+
+		err = os.RemoveAll(s.hostPath(rel))
+		if err != nil {
+			return nil, fmt.Errorf("encountered an error removing `%s`: %w", s.hostPath(rel), err)
+		}
+	}
+
+	err = s.deleteInContainer(containerDeletes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.push(append(pushes, conflicts...))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rel := range pulls {
+		err = s.pull(rel, container[rel], "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// both sides now have the host version of the files unless the container version was copied
+	base := Snapshot{}
+	for rel, entry := range host {
+		base[rel] = entry
+	}
+	for _, rel := range pulls {
+		base[rel] = container[rel]
+	}
+	for _, rel := range hostDeletes {
+		if !contains(pulls, rel) {
+			delete(base, rel)
+		}
+	}
+	// pulled files are extracted into the directories they are in, which may
+	// not have been on the host or been kept when the host removed them
+	for _, rel := range pulls {
+		for dir := parent(rel); dir != ""; dir = parent(dir) {
+			base[dir] = Entry{Dir: true}
+		}
+	}
+	s.Base = base
+
+	result.Pushed = pushes
+	result.Pulled = pulls
+	result.Deleted = append(hostDeletes, containerDeletes...)
+	return result, nil
+}
+
+// scanContainer returns the snapshot of the directory in the container
+func (s *Syncer) scanContainer() (Snapshot, error) {
+	args := []string{"sh", "-c", containerScanScript, "sh", s.ContainerDir}
+
+	// ignored directories are pruned so their files aren't listed
+	if names := s.Ignore.names(); len(names) > 0 {
+		args = append(args, "(")
+		for i, name := range names {
+			if i > 0 {
+				args = append(args, "-o")
+			}
+			args = append(args, "-name", name)
+		}
+		args = append(args, ")", "-prune", "-o")
+	}
+
+	out := &bytes.Buffer{}
+	err := s.ContainerUtil.Exec(containerutil.ExecOptions{Stdout: out}, s.Container, args...)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error listing the files in the container directory `%s`: %w", s.ContainerDir, err)
+	}
+
+	return parseContainerScan(out.String(), s.Ignore)
+}
+
+// deleteInContainer removes the files at the relative paths from the container
+func (s *Syncer) deleteInContainer(rels []string) error {
+	if len(rels) == 0 {
+		return nil
+	}
+
+	args := []string{"rm", "-rf", "--"}
+	for _, rel := range rels {
+		args = append(args, s.containerPath(rel))
+	}
+
+	err := s.ContainerUtil.Exec(containerutil.ExecOptions{}, s.Container, args...)
+	if err != nil {
+		return fmt.Errorf("encountered an error removing files from the container: %w", err)
+	}
+
+	return nil
+}
+
+// push copies the files at the relative paths from the host to the container
+// in a single archive. Directories are copied without what is in them
+func (s *Syncer) push(rels []string) error {
+	if len(rels) == 0 {
+		return nil
+	}
+
+	entries := []archive.Entry{}
+	for _, rel := range rels {
+		entries = append(entries, archive.Entry{Path: s.hostPath(rel), Name: rel, NoRecurse: true})
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(archive.Write(writer, entries, s.Owner))
+	}()
+	defer reader.Close()
+
+	err := s.ContainerUtil.CopyArchiveToContainer(s.Container, s.ContainerDir, reader)
+	if err != nil {
+		return fmt.Errorf("encountered an error copying files to the container: %w", err)
+	}
+
+	return nil
+}
+
+// pull copies the file at the relative path from the container to the host, naming it
+// name instead if it is set. Directories are created without what is in them since the
+// files that are synced in them are pulled separately, unless they are renamed
+func (s *Syncer) pull(rel string, entry Entry, name string) error {
+	targetRel := rel
+	if name != "" {
+		targetRel = path.Join(path.Dir(rel), name)
+	}
+	target := s.hostPath(targetRel)
+
+	// the directories the file is in may have been replaced with symbolic links
+	err := archive.CheckParents(s.HostDir, filepath.FromSlash(targetRel))
+	if err != nil {
+		return err
+	}
+
+	if entry.Dir && name == "" {
+		err := os.MkdirAll(target, 0777)
+		if err != nil {
+			return fmt.Errorf("encountered an error creating the directory `%s`: %w", target, err)
+		}
+		return nil
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(s.ContainerUtil.CopyArchiveFromContainer(s.Container, s.containerPath(rel), writer))
+	}()
+	defer reader.Close()
+
+	// extracting into the synced directory checks every directory the file is in
+	err = archive.Extract(reader, s.HostDir, targetRel)
+	if err != nil {
+		return fmt.Errorf("encountered an error copying `%s` from the container: %w", rel, err)
+	}
+
+	return nil
+}
+
+func (s *Syncer) hostPath(rel string) string {
+	return filepath.Join(s.HostDir, filepath.FromSlash(rel))
+}
+
+func (s *Syncer) containerPath(rel string) string {
+	return path.Join(s.ContainerDir, rel)
+}
+
+// inConflictedDir returns whether or not the path is in one of the conflicted directories
+func inConflictedDir(rel string, conflictedDirs map[string]bool) bool {
+	for dir := parent(rel); dir != ""; dir = parent(dir) {
+		if conflictedDirs[dir] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
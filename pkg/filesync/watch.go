@@ -0,0 +1,85 @@
+package filesync
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch watches the directory on the host and the directories in it that are not ignored.
+// A value is sent on the returned channel when a file that is synced changes. Changes
+// made while the previous one hasn't been received are coalesced. Watching stops when
+// stop is closed. Returns an error if the directory can't be watched
+func Watch(dir string, ignore Ignore, stop <-chan struct{}) (<-chan struct{}, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	err = watchTree(watcher, dir, dir, ignore)
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-stop:
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				rel, err := filepath.Rel(dir, event.Name)
+				if err != nil || ignore.Match(filepath.ToSlash(rel)) {
+					continue
+				}
+
+				// directories aren't watched recursively so new ones are added
+				if event.Op&fsnotify.Create != 0 {
+					if info, err := os.Lstat(event.Name); err == nil && info.IsDir() {
+						watchTree(watcher, dir, event.Name, ignore)
+					}
+				}
+
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			case _, ok := <-watcher.Errors:
+				// the periodic syncs catch any changes that weren't reported
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+// watchTree adds the directory and the directories in it that are not ignored to the watcher
+func watchTree(watcher *fsnotify.Watcher, root string, dir string, ignore Ignore) error {
+	return filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) && file != dir {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			return nil
+		}
+
+		if rel, err := filepath.Rel(root, file); err == nil && rel != "." && ignore.Match(filepath.ToSlash(rel)) {
+			return filepath.SkipDir
+		}
+
+		return watcher.Add(file)
+	})
+}
@@ -0,0 +1,236 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// dockerHubAuthKey is the key Docker Hub credentials are stored under in auth files
+const dockerHubAuthKey = "https://index.docker.io/v1/"
+
+// Credentials represents the credentials used to authenticate with a registry
+type Credentials struct {
+	// The registry the credentials are for
+	Registry string
+	// The username
+	Username string
+	// The password or identity token
+	Password string
+	// Whether or not the credentials are from the Docker config,
+	// which the Docker CLI reads the credentials from itself
+	FromDockerConfig bool
+}
+
+// authFile represents the format shared by the Docker config.json
+// and the containers auth.json files
+type authFile struct {
+	Auths map[string]struct {
+		Auth string `json:"auth"`
+	} `json:"auths"`
+	CredsStore  string            `json:"credsStore"`
+	CredHelpers map[string]string `json:"credHelpers"`
+}
+
+// LoadCredentials finds the credentials for the registry in the standard container
+// auth files. In order of precedence these are $REGISTRY_AUTH_FILE,
+// $XDG_RUNTIME_DIR/containers/auth.json and $DOCKER_CONFIG/config.json, which defaults
+// to ~/.docker/config.json. Credential helpers configured in the files are used.
+// Returns nil if no credentials are found and an error if any occur during the process
+func LoadCredentials(registry string) (*Credentials, error) {
+	dockerConfig := ""
+	if configDir := DockerConfigDir(); configDir != "" {
+		dockerConfig = filepath.Join(configDir, "config.json")
+	}
+
+	for _, path := range authFilePaths() {
+		authBytes, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("encountered an error reading the auth file `%s`: %w", path, err)
+		}
+
+		file := &authFile{}
+		err = json.Unmarshal(authBytes, file)
+		if err != nil {
+			return nil, fmt.Errorf("encountered an error parsing the auth file `%s`: %w", path, err)
+		}
+
+		creds, err := file.credentials(registry)
+		if err != nil {
+			return nil, fmt.Errorf("encountered an error getting credentials for %s from `%s`: %w", registry, path, err)
+		}
+
+		if creds != nil {
+			creds.FromDockerConfig = path == dockerConfig
+			return creds, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func authFilePaths() []string {
+	paths := []string{}
+
+	if path := os.Getenv("REGISTRY_AUTH_FILE"); path != "" {
+		paths = append(paths, path)
+	}
+
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		paths = append(paths, filepath.Join(runtimeDir, "containers", "auth.json"))
+	}
+
+	if configDir := DockerConfigDir(); configDir != "" {
+		paths = append(paths, filepath.Join(configDir, "config.json"))
+	}
+
+	return paths
+}
+
+// DockerConfigDir returns the config directory of the Docker CLI, which is $DOCKER_CONFIG
+// or ~/.docker by default. Empty if neither is set and the home directory is unknown
+func DockerConfigDir() string {
+	if configDir := os.Getenv("DOCKER_CONFIG"); configDir != "" {
+		return configDir
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".docker")
+}
+
+// credentials returns the credentials for the registry from the auth file or nil if there are none
+func (f *authFile) credentials(registry string) (*Credentials, error) {
+	keys := []string{registry, "https://" + registry, "http://" + registry}
+	if registry == DockerHub {
+		keys = []string{dockerHubAuthKey, DockerHub, "index.docker.io"}
+	}
+
+	for _, key := range keys {
+		if helper, ok := f.CredHelpers[key]; ok {
+			return helperCredentials(helper, key, registry)
+		}
+	}
+
+	for _, key := range keys {
+		auth, ok := f.Auths[key]
+		if !ok || auth.Auth == "" {
+			continue
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("encountered an error decoding the auth for %s: %w", key, err)
+		}
+
+		username, password, ok := strings.Cut(string(decoded), ":")
+		if !ok {
+			return nil, fmt.Errorf("the auth for %s is not in the format username:password", key)
+		}
+
+		return &Credentials{Registry: registry, Username: username, Password: password}, nil
+	}
+
+	if f.CredsStore != "" {
+		return helperCredentials(f.CredsStore, keys[0], registry)
+	}
+
+	return nil, nil
+}
+
+// helperCredentials gets credentials from a docker-credential-{helper} executable.
+// Returns nil if the helper has no credentials for the server
+func helperCredentials(helper string, serverURL string, registry string) (*Credentials, error) {
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(serverURL)
+
+	out, err := cmd.Output()
+	if err != nil {
+		if bytes.Contains(out, []byte("credentials not found")) {
+			return nil, nil
+		}
+		if exitErr, ok := err.(*exec.ExitError); ok && bytes.Contains(exitErr.Stderr, []byte("credentials not found")) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("encountered an error running the credential helper docker-credential-%s: %w | out: %s", helper, err, out)
+	}
+
+	parsed := struct {
+		Username string
+		Secret   string
+	}{}
+	err = json.Unmarshal(out, &parsed)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error parsing the output of credential helper docker-credential-%s: %w", helper, err)
+	}
+
+	return &Credentials{Registry: registry, Username: parsed.Username, Password: parsed.Secret}, nil
+}
+
+// AuthHeader returns the value of the Authorization header for basic authentication with the credentials
+func (c *Credentials) AuthHeader() string {
+	return "Basic " + c.encodedAuth()
+}
+
+func (c *Credentials) encodedAuth() string {
+	return base64.StdEncoding.EncodeToString([]byte(c.Username + ":" + c.Password))
+}
+
+// DockerConfig returns the Docker config.json base with the credentials added for their registry.
+// The rest of the base, such as the current context and proxies, is kept. Since the credentials
+// store and credential helpers take precedence over the credentials in the config, the store is
+// removed and so is the helper of the registry. The base may be empty.
+// Returns an error if the base can't be parsed
+func (c *Credentials) DockerConfig(base []byte) ([]byte, error) {
+	key := c.Registry
+	if key == DockerHub {
+		key = dockerHubAuthKey
+	}
+
+	config := map[string]json.RawMessage{}
+	if len(bytes.TrimSpace(base)) > 0 {
+		err := json.Unmarshal(base, &config)
+		if err != nil {
+			return nil, fmt.Errorf("encountered an error parsing the docker config: %w", err)
+		}
+	}
+
+	auths := map[string]json.RawMessage{}
+	credHelpers := map[string]string{}
+	for field, value := range map[string]interface{}{"auths": &auths, "credHelpers": &credHelpers} {
+		if raw, ok := config[field]; ok {
+			err := json.Unmarshal(raw, value)
+			if err != nil {
+				return nil, fmt.Errorf("encountered an error parsing the %s of the docker config: %w", field, err)
+			}
+		}
+	}
+
+	auth, err := json.Marshal(map[string]string{"auth": c.encodedAuth()})
+	if err != nil {
+		return nil, err
+	}
+	auths[key] = auth
+	delete(credHelpers, key)
+	delete(config, "credsStore")
+
+	for field, value := range map[string]interface{}{"auths": auths, "credHelpers": credHelpers} {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		config[field] = raw
+	}
+
+	return json.Marshal(config)
+}
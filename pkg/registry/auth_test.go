@@ -0,0 +1,144 @@
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeAuthFile writes an auth file with the username and password for the registry
+func writeAuthFile(t *testing.T, path string, registry string, username string, password string) {
+	t.Helper()
+
+	auth := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	data, err := json.Marshal(map[string]interface{}{
+		"auths": map[string]interface{}{registry: map[string]string{"auth": auth}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadCredentials(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		registryAuth   bool
+		dockerConfig   bool
+		registry       string
+		want           *Credentials
+		wantDockerAuth bool
+	}{
+		{
+			name:         "docker config",
+			dockerConfig: true,
+			registry:     "registry.example.com",
+			want:         &Credentials{Registry: "registry.example.com", Username: "docker", Password: "dockerpass", FromDockerConfig: true},
+		},
+		{
+			name:         "registry auth file takes precedence",
+			registryAuth: true,
+			dockerConfig: true,
+			registry:     "registry.example.com",
+			want:         &Credentials{Registry: "registry.example.com", Username: "auth", Password: "authpass"},
+		},
+		{
+			name:         "no credentials for the registry",
+			registryAuth: true,
+			dockerConfig: true,
+			registry:     "other.example.com",
+		},
+		{
+			name:     "no auth files",
+			registry: "registry.example.com",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("HOME", dir)
+			t.Setenv("XDG_RUNTIME_DIR", "")
+			t.Setenv("DOCKER_CONFIG", filepath.Join(dir, "docker"))
+			t.Setenv("REGISTRY_AUTH_FILE", filepath.Join(dir, "auth.json"))
+
+			if tc.dockerConfig {
+				writeAuthFile(t, filepath.Join(dir, "docker", "config.json"), "registry.example.com", "docker", "dockerpass")
+			}
+			if tc.registryAuth {
+				writeAuthFile(t, filepath.Join(dir, "auth.json"), "registry.example.com", "auth", "authpass")
+			}
+
+			credentials, err := LoadCredentials(tc.registry)
+			if err != nil {
+				t.Fatalf("unexpected error loading the credentials: %v", err)
+			}
+
+			switch {
+			case tc.want == nil && credentials != nil:
+				t.Errorf("expected no credentials, got %+v", *credentials)
+			case tc.want != nil && credentials == nil:
+				t.Errorf("expected the credentials %+v, got none", *tc.want)
+			case tc.want != nil && *credentials != *tc.want:
+				t.Errorf("expected the credentials %+v, got %+v", *tc.want, *credentials)
+			}
+		})
+	}
+}
+
+func TestDockerConfig(t *testing.T) {
+	base := []byte(`{
+		"currentContext": "colima",
+		"proxies": {"default": {"httpProxy": "http://proxy:3128"}},
+		"credsStore": "desktop",
+		"credHelpers": {"registry.example.com": "ecr-login", "other.example.com": "gcloud"},
+		"auths": {"other.example.com": {"auth": "b3RoZXI6cGFzcw=="}}
+	}`)
+
+	credentials := &Credentials{Registry: "registry.example.com", Username: "user", Password: "pass"}
+	out, err := credentials.DockerConfig(base)
+	if err != nil {
+		t.Fatalf("unexpected error generating the docker config: %v", err)
+	}
+
+	config := struct {
+		CurrentContext string                       `json:"currentContext"`
+		Proxies        map[string]interface{}       `json:"proxies"`
+		CredsStore     string                       `json:"credsStore"`
+		CredHelpers    map[string]string            `json:"credHelpers"`
+		Auths          map[string]map[string]string `json:"auths"`
+	}{}
+	if err := json.Unmarshal(out, &config); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.CurrentContext != "colima" || config.Proxies["default"] == nil {
+		t.Errorf("expected the current context and proxies to be kept, got %s", out)
+	}
+	if config.CredsStore != "" {
+		t.Errorf("expected the credentials store to be removed, got %q", config.CredsStore)
+	}
+	if _, ok := config.CredHelpers["registry.example.com"]; ok || config.CredHelpers["other.example.com"] != "gcloud" {
+		t.Errorf("expected only the credential helper of the registry to be removed, got %v", config.CredHelpers)
+	}
+	if config.Auths["registry.example.com"]["auth"] != credentials.encodedAuth() || config.Auths["other.example.com"]["auth"] == "" {
+		t.Errorf("expected the credentials to be added to the other auths, got %v", config.Auths)
+	}
+
+	out, err = (&Credentials{Registry: DockerHub, Username: "user", Password: "pass"}).DockerConfig(nil)
+	if err != nil {
+		t.Fatalf("unexpected error generating the docker config without a base: %v", err)
+	}
+	if err := json.Unmarshal(out, &config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Auths[dockerHubAuthKey]["auth"] == "" {
+		t.Errorf("expected Docker Hub credentials under %s, got %s", dockerHubAuthKey, out)
+	}
+}
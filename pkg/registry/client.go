@@ -0,0 +1,203 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// dockerHubAPIHost is the host serving the registry API for Docker Hub
+const dockerHubAPIHost = "registry-1.docker.io"
+
+// manifestMediaTypes are the manifest formats accepted when resolving a digest. Manifest
+// lists and indexes are preferred so the digest matches the one recorded by a pull
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+// Client is a minimal client for the OCI distribution API
+type Client struct {
+	// The HTTP client used to make requests
+	HTTPClient *http.Client
+}
+
+// NewClient returns a Client with a default timeout
+func NewClient() *Client {
+	return &Client{
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Digest resolves the current digest of the referenced image in its registry.
+// Credentials are optional and are used if the registry requires authentication.
+// Returns an error if any occur during the process
+func (c *Client) Digest(ref *Reference, credentials *Credentials) (string, error) {
+	if ref.Digest != "" {
+		return ref.Digest, nil
+	}
+
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", baseURL(ref.Registry), ref.Repository, ref.Tag)
+
+	resp, err := c.headManifest(manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		authorization, err := c.authorize(resp.Header.Get("WWW-Authenticate"), credentials)
+		if err != nil {
+			return "", fmt.Errorf("encountered an error authenticating with %s: %w", ref.Registry, err)
+		}
+
+		resp, err = c.headManifest(manifestURL, authorization)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %q resolving the digest of %s", resp.Status, ref)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("the registry %s did not return a digest for %s", ref.Registry, ref)
+	}
+
+	return digest, nil
+}
+
+func (c *Client) headManifest(manifestURL string, authorization string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error creating the manifest request: %w", err)
+	}
+
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error requesting the manifest: %w", err)
+	}
+
+	return resp, nil
+}
+
+// authorize returns the Authorization header value to use for the challenge
+// in the WWW-Authenticate header of an unauthorized response
+func (c *Client) authorize(challenge string, credentials *Credentials) (string, error) {
+	scheme, params := parseChallenge(challenge)
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if credentials == nil {
+			return "", fmt.Errorf("the registry requires credentials but none were found")
+		}
+		return credentials.AuthHeader(), nil
+	case "bearer":
+		return c.bearerToken(params, credentials)
+	default:
+		return "", fmt.Errorf("unsupported authentication scheme %q", scheme)
+	}
+}
+
+// bearerToken fetches a token from the realm of a bearer challenge
+func (c *Client) bearerToken(params map[string]string, credentials *Credentials) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("invalid bearer realm %q", params["realm"])
+	}
+
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("encountered an error creating the token request: %w", err)
+	}
+
+	if credentials != nil {
+		req.Header.Set("Authorization", credentials.AuthHeader())
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("encountered an error requesting a token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %q requesting a token", resp.Status)
+	}
+
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return "", fmt.Errorf("encountered an error parsing the token response: %w", err)
+	}
+
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+
+	return "Bearer " + token.Token, nil
+}
+
+// parseChallenge parses a WWW-Authenticate header such as
+// Bearer realm="https://auth.example.com/token",service="registry"
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := map[string]string{}
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, ", "), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+
+	return scheme, params
+}
+
+// baseURL returns the base URL of the registry API. Registries on the
+// local host are assumed to be served over plain HTTP like the Docker daemon does
+func baseURL(registry string) string {
+	if registry == DockerHub {
+		return "https://" + dockerHubAPIHost
+	}
+
+	host := registry
+	if h, _, err := net.SplitHostPort(registry); err == nil {
+		host = h
+	}
+
+	if host == "localhost" || net.ParseIP(host).IsLoopback() {
+		return "http://" + registry
+	}
+
+	return "https://" + registry
+}
@@ -0,0 +1,110 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	testDigest = "sha256:0123456789abcdef"
+	testToken  = "token"
+)
+
+// newTestRegistry starts a registry stand-in that serves the manifest of team/app:v1.
+// The auth is none, basic or bearer. Credentials are testuser:testpass
+func newTestRegistry(t *testing.T, auth string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	validBasic := (&Credentials{Username: "testuser", Password: "testpass"}).AuthHeader()
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != validBasic {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("scope") != "repository:team/app:pull" || r.URL.Query().Get("service") != "test-registry" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": testToken})
+	})
+
+	mux.HandleFunc("/v2/team/app/manifests/v1", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		switch auth {
+		case "basic":
+			if r.Header.Get("Authorization") != validBasic {
+				w.Header().Set("WWW-Authenticate", `Basic realm="test-registry"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		case "bearer":
+			if r.Header.Get("Authorization") != "Bearer "+testToken {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test-registry",scope="repository:team/app:pull"`, server.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+
+		w.Header().Set("Docker-Content-Digest", testDigest)
+	})
+
+	return server
+}
+
+func TestClientDigest(t *testing.T) {
+	credentials := &Credentials{Username: "testuser", Password: "testpass"}
+
+	for _, tc := range []struct {
+		name        string
+		auth        string
+		ref         string
+		credentials *Credentials
+		want        string
+		wantErr     bool
+	}{
+		{name: "anonymous", auth: "none", ref: "team/app:v1", want: testDigest},
+		{name: "basic auth", auth: "basic", ref: "team/app:v1", credentials: credentials, want: testDigest},
+		{name: "bearer token", auth: "bearer", ref: "team/app:v1", credentials: credentials, want: testDigest},
+		{name: "basic auth without credentials", auth: "basic", ref: "team/app:v1", wantErr: true},
+		{name: "bearer token with wrong credentials", auth: "bearer", ref: "team/app:v1", credentials: &Credentials{Username: "testuser", Password: "wrong"}, wantErr: true},
+		{name: "unknown tag", auth: "none", ref: "team/app:v2", wantErr: true},
+		{name: "pinned reference", auth: "none", ref: "team/app@sha256:fedcba", want: "sha256:fedcba"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestRegistry(t, tc.auth)
+
+			ref, err := ParseReference(strings.TrimPrefix(server.URL, "http://") + "/" + tc.ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			digest, err := NewClient().Digest(ref, tc.credentials)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got the digest %q", digest)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error resolving the digest: %v", err)
+			}
+			if digest != tc.want {
+				t.Errorf("expected the digest %q, got %q", tc.want, digest)
+			}
+		})
+	}
+}
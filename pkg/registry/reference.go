@@ -0,0 +1,85 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// DockerHub is the registry used for references without a registry host
+	DockerHub = "docker.io"
+
+	defaultTag = "latest"
+)
+
+// Reference represents a parsed image reference
+// such as registry.example.com/team/image:tag
+type Reference struct {
+	// The registry host, e.g. docker.io or localhost:5000
+	Registry string
+	// The repository within the registry, e.g. library/alpine
+	Repository string
+	// The image tag. Empty if the reference is pinned by digest only
+	Tag string
+	// The image digest, e.g. sha256:... Empty if the reference is not pinned
+	Digest string
+}
+
+// ParseReference parses an image reference. References without a registry host
+// refer to Docker Hub and references without a tag or digest use the latest tag.
+// Returns an error if the reference is invalid
+func ParseReference(ref string) (*Reference, error) {
+	if ref == "" || strings.ContainsAny(ref, " \t\n") {
+		return nil, fmt.Errorf("invalid image reference %q", ref)
+	}
+
+	parsed := &Reference{}
+
+	remainder := ref
+	if name, digest, ok := strings.Cut(remainder, "@"); ok {
+		if !strings.Contains(digest, ":") {
+			return nil, fmt.Errorf("invalid digest in image reference %q", ref)
+		}
+		remainder, parsed.Digest = name, digest
+	}
+
+	// a colon after the last slash separates the tag, any
+	// other colon is part of the registry host's port
+	if sep := strings.LastIndex(remainder, ":"); sep > strings.LastIndex(remainder, "/") {
+		remainder, parsed.Tag = remainder[:sep], remainder[sep+1:]
+	}
+
+	host, path, ok := strings.Cut(remainder, "/")
+	if ok && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		parsed.Registry, parsed.Repository = host, path
+	} else {
+		parsed.Registry, parsed.Repository = DockerHub, remainder
+		if !strings.Contains(remainder, "/") {
+			parsed.Repository = "library/" + remainder
+		}
+	}
+
+	if parsed.Repository == "" {
+		return nil, fmt.Errorf("invalid image reference %q", ref)
+	}
+
+	if parsed.Tag == "" && parsed.Digest == "" {
+		parsed.Tag = defaultTag
+	}
+
+	return parsed, nil
+}
+
+// String returns the fully qualified reference
+func (r *Reference) String() string {
+	ref := fmt.Sprintf("%s/%s", r.Registry, r.Repository)
+	if r.Tag != "" {
+		ref += ":" + r.Tag
+	}
+
+	if r.Digest != "" {
+		ref += "@" + r.Digest
+	}
+
+	return ref
+}
@@ -0,0 +1,188 @@
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// templateExt is the extension of template files. It is removed from the name of rendered files
+const templateExt = ".tmpl"
+
+//go:embed templates
+var builtinTemplates embed.FS
+
+// Toolchain represents a language toolchain detected in a project
+type Toolchain struct {
+	// The name of the toolchain, e.g. go
+	Name string
+	// The file the toolchain was detected from, e.g. go.mod
+	File string
+	// The proposed base image for the toolchain
+	BaseImage string
+}
+
+// Project represents the values used to render the templates
+type Project struct {
+	// The name of the workspace
+	Name string
+	// The base image of the Containerfile
+	BaseImage string
+	// The toolchains detected in the project directory
+	Toolchains []Toolchain
+	// The non-root user created in the image
+	User string
+	// The working directory in the container
+	Workdir string
+	// The command that creates the non-root user in the base image
+	AddUser string
+}
+
+// detector detects a toolchain from the contents of a file in the project directory
+type detector struct {
+	name     string
+	file     string
+	image    string
+	version  *regexp.Regexp
+	imageFmt string
+}
+
+// detectors are checked in order, so the first detected toolchain is the proposed one
+var detectors = []detector{
+	{name: "go", file: "go.mod", image: "golang:latest", version: regexp.MustCompile(`(?m)^go (\d+\.\d+)`), imageFmt: "golang:%s"},
+	{name: "node", file: "package.json", image: "node:lts", version: regexp.MustCompile(`"node"\s*:\s*"[^"\d]*(\d+)`), imageFmt: "node:%s"},
+	{name: "python", file: "requirements.txt", image: "python:3"},
+	{name: "rust", file: "Cargo.toml", image: "rust:latest", version: regexp.MustCompile(`(?m)^rust-version\s*=\s*"(\d+\.\d+)`), imageFmt: "rust:%s"},
+}
+
+// defaultBaseImage is the base image proposed when no toolchain is detected
+const defaultBaseImage = "debian:stable-slim"
+
+// Detect inspects the project directory and proposes a Project
+// for it. Returns an error if any occur during the process
+func Detect(dir string) (*Project, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error getting the absolute path of `%s`: %w", dir, err)
+	}
+
+	project := &Project{
+		Name:      strings.ToLower(filepath.Base(abs)),
+		BaseImage: defaultBaseImage,
+		User:      "cadeuser",
+	}
+
+	for _, d := range detectors {
+		content, err := os.ReadFile(filepath.Join(abs, d.file))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("encountered an error reading `%s`: %w", d.file, err)
+		}
+
+		image := d.image
+		if d.version != nil {
+			if match := d.version.FindSubmatch(content); match != nil {
+				image = fmt.Sprintf(d.imageFmt, match[1])
+			}
+		}
+
+		project.Toolchains = append(project.Toolchains, Toolchain{Name: d.name, File: d.file, BaseImage: image})
+	}
+
+	if len(project.Toolchains) > 0 {
+		project.BaseImage = project.Toolchains[0].BaseImage
+	}
+
+	return project, nil
+}
+
+// Render renders the built-in templates followed by the templates in each of the
+// template directories, so files in later directories replace files with the same
+// name. Returns the rendered files keyed by file name and an error if any occur
+func Render(project *Project, templateDirs ...string) (map[string][]byte, error) {
+	if project.Workdir == "" {
+		project.Workdir = fmt.Sprintf("/home/%s/workdir", project.User)
+	}
+
+	if project.AddUser == "" {
+		project.AddUser = addUserCommand(project.BaseImage, project.User)
+	}
+
+	templates := map[string]string{}
+
+	builtin, err := fs.Sub(builtinTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+
+	err = readTemplates(builtin, templates)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dir := range templateDirs {
+		err = readTemplates(os.DirFS(dir), templates)
+		if err != nil {
+			return nil, fmt.Errorf("encountered an error reading the templates in `%s`: %w", dir, err)
+		}
+	}
+
+	names := []string{}
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rendered := map[string][]byte{}
+	for _, name := range names {
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(templates[name])
+		if err != nil {
+			return nil, fmt.Errorf("encountered an error parsing the template %s: %w", name, err)
+		}
+
+		out := &bytes.Buffer{}
+		err = tmpl.Execute(out, project)
+		if err != nil {
+			return nil, fmt.Errorf("encountered an error rendering the template %s: %w", name, err)
+		}
+
+		rendered[strings.TrimSuffix(name, templateExt)] = out.Bytes()
+	}
+
+	return rendered, nil
+}
+
+// readTemplates reads every file in the file system into the templates, keyed by its path
+func readTemplates(fsys fs.FS, templates map[string]string) error {
+	return fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		content, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+
+		templates[path] = string(content)
+		return nil
+	})
+}
+
+// addUserCommand returns the command that creates a non-root user in the base image.
+// Alpine based images use busybox which has a different adduser than Debian based images
+func addUserCommand(baseImage string, user string) string {
+	if strings.Contains(baseImage, "alpine") {
+		return fmt.Sprintf("adduser -D -s /bin/sh %s", user)
+	}
+
+	return fmt.Sprintf("useradd --create-home --shell /bin/bash %s", user)
+}
@@ -0,0 +1,142 @@
+package settings
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// WorkspaceRootEnv is the environment variable that can be used
+	// to override the workspace root from the settings file
+	WorkspaceRootEnv = "CADE_WORKSPACE_ROOT"
+
+	defaultShell          = "/bin/sh"
+	defaultTrashRetention = 7 * 24 * time.Hour
+	defaultImageNamespace = "cade"
+)
+
+// Settings represents the global, per-user cade settings.
+// They are read from $XDG_CONFIG_HOME/cade/config.yaml
+type Settings struct {
+	// The directory that workspace working directories are created in
+	WorkspaceRoot string `json:"workspace_root" yaml:"workspace_root"`
+	// The container runtime to use
+	Runtime string `json:"runtime" yaml:"runtime"`
+	// The shell started by `cade term`
+	Shell string `json:"shell" yaml:"shell"`
+	// The network used when a workspace config does not specify one
+	Network string `json:"network" yaml:"network"`
+	// How long removed workspace directories are kept in the trash, e.g. 168h
+	TrashRetention time.Duration `json:"trash_retention" yaml:"trash_retention"`
+	// The namespace that images built for workspaces are tagged under
+	ImageNamespace string `json:"image_namespace" yaml:"image_namespace"`
+	// The dotfiles installed in every workspace
+	Dotfiles Dotfiles `json:"dotfiles" yaml:"dotfiles"`
+}
+
+// Dotfiles represents the dotfiles installed in the home directory of workspace containers
+type Dotfiles struct {
+	// A local directory or the URL of a git repository. No dotfiles are installed if empty
+	Source string `json:"source" yaml:"source"`
+	// The command run in the dotfiles directory to install them. Defaults to the first
+	// of install.sh, install, bootstrap.sh, bootstrap, setup.sh and setup that exists,
+	// or linking the dotfiles into the home directory if there are none
+	Install string `json:"install" yaml:"install"`
+}
+
+// Path returns the path of the user settings file
+func Path() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("encountered an error getting the user config directory: %w", err)
+	}
+
+	return filepath.Join(configDir, "cade", "config.yaml"), nil
+}
+
+// Load reads the user settings file, if it exists, and applies
+// defaults and environment variable overrides.
+// Returns an error if any occur during the process
+func Load() (*Settings, error) {
+	settings := &Settings{}
+
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	settingsBytes, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("encountered an error reading the cade settings file `%s`: %w", path, err)
+	}
+
+	if err == nil {
+		err = yaml.Unmarshal(settingsBytes, settings)
+		if err != nil {
+			return nil, fmt.Errorf("encountered an error parsing the cade settings file `%s`: %w", path, err)
+		}
+	}
+
+	if root := os.Getenv(WorkspaceRootEnv); root != "" {
+		settings.WorkspaceRoot = root
+	}
+
+	if settings.WorkspaceRoot == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("encountered an error getting the user home directory: %w", err)
+		}
+
+		settings.WorkspaceRoot = filepath.Join(home, "cade", "workspaces")
+	}
+
+	settings.WorkspaceRoot, err = expandHome(settings.WorkspaceRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.Shell == "" {
+		settings.Shell = defaultShell
+	}
+
+	if settings.TrashRetention == 0 {
+		settings.TrashRetention = defaultTrashRetention
+	}
+
+	if settings.ImageNamespace == "" {
+		settings.ImageNamespace = defaultImageNamespace
+	}
+
+	// only local paths are expanded, repository URLs are left as they are
+	settings.Dotfiles.Source, err = expandHome(settings.Dotfiles.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// WorkspaceDir returns the working directory on the host
+// for the workspace with the provided name
+func (s *Settings) WorkspaceDir(workspaceName string) string {
+	return filepath.Join(s.WorkspaceRoot, workspaceName)
+}
+
+// expandHome replaces a leading `~` in the path with the user home directory
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("encountered an error getting the user home directory: %w", err)
+	}
+
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
@@ -0,0 +1,73 @@
+package sshproxy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HostPrefix is the prefix of the SSH host alias of every workspace
+const HostPrefix = "cade-"
+
+// HostAlias returns the SSH host alias of the workspace with the provided name
+func HostAlias(workspaceName string) string {
+	return HostPrefix + workspaceName
+}
+
+// ConfigEntry returns the SSH config entry that connects to the workspace by running
+// the executable as its proxy command. The host key is checked against the known hosts file
+func ConfigEntry(workspaceName string, executable string, knownHosts string) string {
+	lines := []string{
+		fmt.Sprintf("Host %s", HostAlias(workspaceName)),
+		fmt.Sprintf("\tHostName %s", workspaceName),
+		fmt.Sprintf("\tHostKeyAlias %s", HostAlias(workspaceName)),
+		fmt.Sprintf("\tUserKnownHostsFile %q", knownHosts),
+		"\tStrictHostKeyChecking yes",
+		fmt.Sprintf("\tProxyCommand %q ssh-proxy %%h", executable),
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// UpdateConfig adds the entry of the workspace to the SSH config at the path, replacing
+// the entry previously added for the workspace. The file is created if it does not exist
+func UpdateConfig(path string, workspaceName string, entry string) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("encountered an error reading the SSH config `%s`: %w", path, err)
+	}
+
+	begin := fmt.Sprintf("# BEGIN cade workspace %s\n", workspaceName)
+	end := fmt.Sprintf("# END cade workspace %s\n", workspaceName)
+	block := begin + entry + end
+
+	config := string(existing)
+	if start := strings.Index(config, begin); start >= 0 {
+		if stop := strings.Index(config[start:], end); stop >= 0 {
+			config = config[:start] + block + config[start+stop+len(end):]
+		} else {
+			return fmt.Errorf("the SSH config `%s` has the line %q without a matching %q", path, strings.TrimSpace(begin), strings.TrimSpace(end))
+		}
+	} else {
+		if config != "" && !strings.HasSuffix(config, "\n") {
+			config += "\n"
+		}
+		if config != "" {
+			config += "\n"
+		}
+		config += block
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return fmt.Errorf("encountered an error ensuring the directory of the SSH config `%s` exists: %w", path, err)
+	}
+
+	err = os.WriteFile(path, []byte(config), 0600)
+	if err != nil {
+		return fmt.Errorf("encountered an error writing the SSH config `%s`: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package sshproxy
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// LoadHostKey loads the host key of the SSH proxy from the path.
+// A new key is generated and written to the path if it does not exist
+func LoadHostKey(path string) (ssh.Signer, error) {
+	keyBytes, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		keyBytes, err = generateHostKey(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error reading the SSH host key `%s`: %w", path, err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(keyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error parsing the SSH host key `%s`: %w", path, err)
+	}
+
+	return signer, nil
+}
+
+// generateHostKey generates an ed25519 key and writes it to the path in the PEM format
+func generateHostKey(path string) ([]byte, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	keyBytes := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	return keyBytes, os.WriteFile(path, keyBytes, 0600)
+}
+
+// KnownHostsLine returns the known_hosts line trusting the key for the hosts matching the pattern
+func KnownHostsLine(pattern string, key ssh.PublicKey) string {
+	return pattern + " " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+}
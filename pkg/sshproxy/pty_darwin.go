@@ -0,0 +1,43 @@
+package sshproxy
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// openPty opens a new pseudo terminal and returns its master and slave ends
+func openPty() (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encountered an error opening a pseudo terminal: %w", err)
+	}
+
+	fd := int(master.Fd())
+	for _, request := range []uint{unix.TIOCPTYGRANT, unix.TIOCPTYUNLK} {
+		err = unix.IoctlSetInt(fd, request, 0)
+		if err != nil {
+			master.Close()
+			return nil, nil, fmt.Errorf("encountered an error unlocking the pseudo terminal: %w", err)
+		}
+	}
+
+	name := make([]byte, 128)
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), uintptr(unix.TIOCPTYGNAME), uintptr(unsafe.Pointer(&name[0])))
+	if errno != 0 {
+		master.Close()
+		return nil, nil, fmt.Errorf("encountered an error getting the pseudo terminal name: %w", errno)
+	}
+
+	slave, err := os.OpenFile(string(name[:bytes.IndexByte(name, 0)]), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("encountered an error opening the pseudo terminal: %w", err)
+	}
+
+	return master, slave, nil
+}
@@ -0,0 +1,23 @@
+//go:build !linux && !darwin
+
+package sshproxy
+
+import (
+	"errors"
+	"os"
+)
+
+// ptySupported is whether or not pseudo terminals can be opened on this platform
+const ptySupported = false
+
+var errPtyUnsupported = errors.New("pseudo terminals are not supported on this platform")
+
+// openPty opens a new pseudo terminal and returns its master and slave ends
+func openPty() (*os.File, *os.File, error) {
+	return nil, nil, errPtyUnsupported
+}
+
+// resizePty sets the size of the pseudo terminal
+func resizePty(master *os.File, columns uint32, rows uint32) error {
+	return errPtyUnsupported
+}
@@ -0,0 +1,20 @@
+//go:build linux || darwin
+
+package sshproxy
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// ptySupported is whether or not pseudo terminals can be opened on this platform
+const ptySupported = true
+
+// resizePty sets the size of the pseudo terminal
+func resizePty(master *os.File, columns uint32, rows uint32) error {
+	return unix.IoctlSetWinsize(int(master.Fd()), unix.TIOCSWINSZ, &unix.Winsize{
+		Col: uint16(columns),
+		Row: uint16(rows),
+	})
+}
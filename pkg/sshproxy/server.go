@@ -0,0 +1,404 @@
+package sshproxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/everettraven/cade/pkg/containerutil"
+	"golang.org/x/crypto/ssh"
+)
+
+// sftpServerScript starts the sftp server of the image from one of its common locations
+const sftpServerScript = `for server in /usr/lib/openssh/sftp-server /usr/libexec/openssh/sftp-server /usr/lib/ssh/sftp-server /usr/libexec/sftp-server /usr/lib/sftp-server; do
+	if [ -x "$server" ]; then
+		exec "$server"
+	fi
+done
+echo "cade: sftp is not available because the workspace image has no sftp-server" >&2
+exit 127
+`
+
+// relayScript relays its input and output to the TCP port of the host passed as the
+// arguments with the first tool the image has that can open a TCP connection
+const relayScript = `host="$1"
+port="$2"
+if command -v socat >/dev/null 2>&1; then
+	exec socat - "TCP:$host:$port"
+elif command -v nc >/dev/null 2>&1; then
+	exec nc "$host" "$port"
+elif command -v python3 >/dev/null 2>&1; then
+	exec python3 -c '
+import socket, sys, threading
+s = socket.create_connection((sys.argv[1], int(sys.argv[2])))
+def send():
+    while True:
+        data = sys.stdin.buffer.read1(65536)
+        if not data:
+            break
+        s.sendall(data)
+    s.shutdown(socket.SHUT_WR)
+threading.Thread(target=send, daemon=True).start()
+while True:
+    data = s.recv(65536)
+    if not data:
+        break
+    sys.stdout.buffer.write(data)
+    sys.stdout.buffer.flush()
+' "$host" "$port"
+elif command -v bash >/dev/null 2>&1; then
+	exec bash -c 'exec 3<>"/dev/tcp/$0/$1"; cat <&3 & cat >&3' "$host" "$port"
+fi
+echo "cade: port forwarding requires socat, nc, python3 or bash in the workspace" >&2
+exit 127
+`
+
+// Server serves SSH connections to a workspace container. Every session and
+// forwarded port is run with ContainerUtil.Exec so the image doesn't need an SSH
+// server. Clients are not authenticated because the connection is expected to be
+// the standard streams of a proxy command started by the user's SSH client
+type Server struct {
+	// The ContainerUtil used to run commands in the container
+	ContainerUtil containerutil.ContainerUtil
+	// The name of the workspace container
+	Container string
+	// The shell started for sessions that don't run a command
+	Shell string
+	// Environment variables set for every command
+	Env map[string]string
+	// The host key of the server
+	HostKey ssh.Signer
+}
+
+// Serve serves the SSH connection until the client disconnects.
+// Returns an error if the SSH handshake fails
+func (s *Server) Serve(conn net.Conn) error {
+	config := &ssh.ServerConfig{
+		NoClientAuth: true,
+	}
+	config.AddHostKey(s.HostKey)
+
+	serverConn, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return fmt.Errorf("encountered an error during the SSH handshake: %w", err)
+	}
+	defer serverConn.Close()
+
+	// remote port forwarding is not supported
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		switch newChannel.ChannelType() {
+		case "session":
+			go s.handleSession(newChannel)
+		case "direct-tcpip":
+			go s.handleDirectTCPIP(newChannel)
+		default:
+			newChannel.Reject(ssh.UnknownChannelType, fmt.Sprintf("unsupported channel type %q", newChannel.ChannelType()))
+		}
+	}
+
+	return nil
+}
+
+// session is the state of an SSH session channel
+type session struct {
+	channel ssh.Channel
+	env     map[string]string
+	master  *os.File
+	slave   *os.File
+	resized chan struct{}
+	started bool
+}
+
+func (s *Server) handleSession(newChannel ssh.NewChannel) {
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+
+	sess := &session{
+		channel: channel,
+		env:     map[string]string{},
+		resized: make(chan struct{}, 1),
+	}
+	for name, value := range s.Env {
+		sess.env[name] = value
+	}
+	defer sess.closePty()
+
+	done := make(chan struct{})
+	for {
+		select {
+		case req, ok := <-requests:
+			if !ok {
+				return
+			}
+			s.handleSessionRequest(sess, req, done)
+		case <-done:
+			return
+		}
+	}
+}
+
+// handleSessionRequest handles a request on a session channel. done is closed when the command
+// started by a shell, exec or subsystem request exits and the channel has been closed
+func (s *Server) handleSessionRequest(sess *session, req *ssh.Request, done chan struct{}) {
+	switch req.Type {
+	case "env":
+		var payload struct {
+			Name  string
+			Value string
+		}
+		if sess.started || ssh.Unmarshal(req.Payload, &payload) != nil {
+			req.Reply(false, nil)
+			return
+		}
+		sess.env[payload.Name] = payload.Value
+		req.Reply(true, nil)
+	case "pty-req":
+		var payload struct {
+			Term    string
+			Columns uint32
+			Rows    uint32
+			Width   uint32
+			Height  uint32
+			Modes   string
+		}
+		// the client continues without a terminal when the request is rejected
+		if !ptySupported || sess.started || sess.master != nil || ssh.Unmarshal(req.Payload, &payload) != nil {
+			req.Reply(false, nil)
+			return
+		}
+
+		master, slave, err := openPty()
+		if err != nil {
+			req.Reply(false, nil)
+			return
+		}
+		sess.master, sess.slave = master, slave
+		resizePty(master, payload.Columns, payload.Rows)
+		sess.env["TERM"] = payload.Term
+		req.Reply(true, nil)
+	case "window-change":
+		var payload struct {
+			Columns uint32
+			Rows    uint32
+			Width   uint32
+			Height  uint32
+		}
+		if sess.master == nil || ssh.Unmarshal(req.Payload, &payload) != nil {
+			return
+		}
+		if resizePty(sess.master, payload.Columns, payload.Rows) == nil {
+			select {
+			case sess.resized <- struct{}{}:
+			default:
+			}
+		}
+	case "shell", "exec", "subsystem":
+		command, ok := s.command(req)
+		if !ok || sess.started {
+			req.Reply(false, nil)
+			return
+		}
+		sess.started = true
+		req.Reply(true, nil)
+
+		env := map[string]string{}
+		for name, value := range sess.env {
+			env[name] = value
+		}
+
+		go func() {
+			status := s.run(sess, command, env)
+			sess.channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+			sess.channel.Close()
+			close(done)
+		}()
+	default:
+		req.Reply(false, nil)
+	}
+}
+
+// command returns the command run in the container for a shell, exec or subsystem request
+func (s *Server) command(req *ssh.Request) ([]string, bool) {
+	switch req.Type {
+	case "shell":
+		return []string{s.Shell}, true
+	case "exec":
+		var payload struct{ Command string }
+		if ssh.Unmarshal(req.Payload, &payload) != nil {
+			return nil, false
+		}
+		return []string{"sh", "-c", payload.Command}, true
+	case "subsystem":
+		var payload struct{ Name string }
+		if ssh.Unmarshal(req.Payload, &payload) != nil || payload.Name != "sftp" {
+			return nil, false
+		}
+		return []string{"sh", "-c", sftpServerScript}, true
+	default:
+		return nil, false
+	}
+}
+
+// run runs the command of the session in the container and returns its exit status
+func (s *Server) run(sess *session, command []string, env map[string]string) uint32 {
+	execOpts := containerutil.ExecOptions{
+		Interactive: true,
+		Env:         env,
+	}
+
+	output := &sync.WaitGroup{}
+	if sess.master != nil {
+		execOpts.Tty = true
+		execOpts.Stdin, execOpts.Stdout, execOpts.Stderr = sess.slave, sess.slave, sess.slave
+		execOpts.Resized = sess.resized
+
+		go io.Copy(sess.master, sess.channel)
+		output.Add(1)
+		go func() {
+			defer output.Done()
+			io.Copy(sess.channel, sess.master)
+		}()
+	} else {
+		stdin, err := pipeInput(sess.channel)
+		if err != nil {
+			fmt.Fprintln(sess.channel.Stderr(), "cade:", err)
+			return 255
+		}
+		defer stdin.Close()
+
+		execOpts.Stdin = stdin
+		execOpts.Stdout = sess.channel
+		execOpts.Stderr = sess.channel.Stderr()
+	}
+
+	err := s.ContainerUtil.Exec(execOpts, s.Container, command...)
+
+	if sess.slave != nil {
+		// the output is read until the master end reports that the slave end
+		// is closed. A short wait bounds platforms that never report it
+		sess.slave.Close()
+		sess.slave = nil
+		waitTimeout(output, 5*time.Second)
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return uint32(exitErr.ExitCode())
+	} else if err != nil {
+		fmt.Fprintln(sess.channel.Stderr(), "cade:", err)
+		return 255
+	}
+
+	return 0
+}
+
+func (sess *session) closePty() {
+	if sess.slave != nil {
+		sess.slave.Close()
+	}
+	if sess.master != nil {
+		sess.master.Close()
+	}
+}
+
+// handleDirectTCPIP forwards a connection from the client to a port in the container
+func (s *Server) handleDirectTCPIP(newChannel ssh.NewChannel) {
+	var payload struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, "invalid direct-tcpip request")
+		return
+	}
+
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+	go ssh.DiscardRequests(requests)
+
+	stdin, err := pipeInput(channel)
+	if err != nil {
+		return
+	}
+	defer stdin.Close()
+
+	execOpts := containerutil.ExecOptions{
+		Interactive: true,
+		Stdin:       stdin,
+		Stdout:      channel,
+		Stderr:      os.Stderr,
+	}
+
+	s.ContainerUtil.Exec(execOpts, s.Container, "sh", "-c", relayScript, "sh", payload.Host, strconv.Itoa(int(payload.Port)))
+	channel.CloseWrite()
+}
+
+// pipeInput returns a pipe the input from the channel is copied to. A pipe is used rather than
+// the channel itself because commands given an io.Reader as their input wait for it to be closed
+// before they return, which the client only does when it is done with the channel
+func pipeInput(channel ssh.Channel) (*os.File, error) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error creating a pipe for the input of the command: %w", err)
+	}
+
+	go func() {
+		io.Copy(writer, channel)
+		writer.Close()
+	}()
+
+	return reader, nil
+}
+
+// waitTimeout waits for the wait group until the timeout passes
+func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+// stdioConn is a net.Conn over a reader and writer such as the standard streams of the process
+type stdioConn struct {
+	io.Reader
+	io.Writer
+}
+
+// NewStdioConn returns a connection that reads from the reader and writes to the writer
+func NewStdioConn(reader io.Reader, writer io.Writer) net.Conn {
+	return &stdioConn{Reader: reader, Writer: writer}
+}
+
+func (c *stdioConn) Close() error                       { return nil }
+func (c *stdioConn) LocalAddr() net.Addr                { return stdioAddr{} }
+func (c *stdioConn) RemoteAddr() net.Addr               { return stdioAddr{} }
+func (c *stdioConn) SetDeadline(t time.Time) error      { return nil }
+func (c *stdioConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *stdioConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type stdioAddr struct{}
+
+func (stdioAddr) Network() string { return "stdio" }
+func (stdioAddr) String() string  { return "stdio" }
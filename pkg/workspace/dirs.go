@@ -0,0 +1,79 @@
+package workspace
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Dir represents a workspace working directory on the host
+type Dir struct {
+	// The name of the workspace
+	Workspace string
+	// The path to the directory
+	Path string
+	// When the directory was last modified
+	ModTime time.Time
+}
+
+// ListDirs returns the workspace working directories under the workspace root.
+// Returns an error if any occur during the process
+func ListDirs(workspaceRoot string) ([]Dir, error) {
+	dirs := []Dir{}
+
+	entries, err := os.ReadDir(workspaceRoot)
+	if os.IsNotExist(err) {
+		return dirs, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("encountered an error reading the workspace root `%s`: %w", workspaceRoot, err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == TrashDirName || entry.Name() == LogsDirName {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("encountered an error getting information about `%s`: %w", entry.Name(), err)
+		}
+
+		dirs = append(dirs, Dir{
+			Workspace: entry.Name(),
+			Path:      filepath.Join(workspaceRoot, entry.Name()),
+			ModTime:   info.ModTime(),
+		})
+	}
+
+	return dirs, nil
+}
+
+// DirSize returns the total size in bytes of the files in the directory.
+// Returns an error if any occur during the process
+func DirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("encountered an error calculating the size of `%s`: %w", dir, err)
+	}
+
+	return size, nil
+}
@@ -0,0 +1,151 @@
+package workspace
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// dockerignoreFile is the name of the file in a build context with the patterns
+// of the files in it that are not sent to the container runtime when building
+const dockerignoreFile = ".dockerignore"
+
+// dockerignorePattern is a pattern of a .dockerignore file
+type dockerignorePattern struct {
+	regexp *regexp.Regexp
+	// exclusion is set for patterns starting with ! that add back files a previous pattern ignored
+	exclusion bool
+}
+
+// dockerignore are the patterns of a .dockerignore file. Like the container runtime,
+// patterns are matched against the slash separated path relative to the build context
+// and the directories it is in, and the last pattern that matches decides
+type dockerignore []dockerignorePattern
+
+// loadDockerignore reads the .dockerignore file in the build context directory.
+// Empty if there is none. Returns an error if any occur during the process
+func loadDockerignore(context string) (dockerignore, error) {
+	file, err := os.Open(filepath.Join(context, dockerignoreFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("encountered an error opening the %s of the context `%s`: %w", dockerignoreFile, context, err)
+	}
+	defer file.Close()
+
+	ignore := dockerignore{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pattern := dockerignorePattern{}
+		if strings.HasPrefix(line, "!") {
+			pattern.exclusion = true
+			line = strings.TrimSpace(line[1:])
+		}
+
+		line = strings.TrimPrefix(path.Clean(filepath.ToSlash(line)), "/")
+		if line == "" || line == "." {
+			continue
+		}
+
+		pattern.regexp, err = regexp.Compile(dockerignoreRegexp(line))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q in the %s of the context `%s`: %w", line, dockerignoreFile, context, err)
+		}
+		ignore = append(ignore, pattern)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("encountered an error reading the %s of the context `%s`: %w", dockerignoreFile, context, err)
+	}
+
+	return ignore, nil
+}
+
+// Match returns whether or not the file at the slash separated path relative to the build context is ignored
+func (d dockerignore) Match(rel string) bool {
+	ignored := false
+	for _, pattern := range d {
+		if pattern.exclusion != ignored {
+			continue
+		}
+
+		for dir := rel; dir != "."; dir = path.Dir(dir) {
+			if pattern.regexp.MatchString(dir) {
+				ignored = !pattern.exclusion
+				break
+			}
+		}
+	}
+
+	return ignored
+}
+
+// hasExclusions returns whether or not a pattern adds back files. The directories
+// that are ignored can then still have files in them that are not
+func (d dockerignore) hasExclusions() bool {
+	for _, pattern := range d {
+		if pattern.exclusion {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dockerignoreRegexp converts the pattern to a regular expression. * and ? don't match /, ** matches
+// any number of directories and character classes and \ escapes are kept, like the container runtime
+func dockerignoreRegexp(pattern string) string {
+	var expr strings.Builder
+	expr.WriteString("^")
+
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					expr.WriteString("(.*/)?")
+				} else {
+					expr.WriteString(".*")
+				}
+				continue
+			}
+			expr.WriteString("[^/]*")
+		case '?':
+			expr.WriteString("[^/]")
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			expr.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				expr.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "^") || strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	expr.WriteString("$")
+	return expr.String()
+}
@@ -0,0 +1,100 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDockerignoreMatch(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		patterns string
+		ignored  []string
+		kept     []string
+	}{
+		{
+			name:     "a star doesn't cross directories",
+			patterns: "*/temp*\n*/*/tmp?",
+			ignored:  []string{"somedir/temporary.txt", "somedir/temp", "a/b/tmp1", "somedir/temp/file"},
+			kept:     []string{"temp", "a/b/c/temp", "a/b/tmp12"},
+		},
+		{
+			name:     "a double star matches any number of directories",
+			patterns: "**/*.go",
+			ignored:  []string{"main.go", "pkg/cmd/up.go", "a/b/c/d.go"},
+			kept:     []string{"go.mod", "pkg/cmd/up.go.txt"},
+		},
+		{
+			name:     "an exclusion adds back a file",
+			patterns: "*.md\n!keep.md",
+			ignored:  []string{"README.md", "CHANGELOG.md"},
+			kept:     []string{"keep.md", "docs/README.md", "main.go"},
+		},
+		{
+			name:     "the last matching pattern decides",
+			patterns: "*.md\n!README*.md\nREADME-secret.md",
+			ignored:  []string{"CHANGELOG.md", "README-secret.md"},
+			kept:     []string{"README.md", "README-public.md"},
+		},
+		{
+			name:     "a trailing slash matches the directory and everything in it",
+			patterns: "build/\n/dist",
+			ignored:  []string{"build", "build/out/app", "dist/app.js"},
+			kept:     []string{"builder", "src/build/app", "src/dist"},
+		},
+		{
+			name:     "an exclusion adds back a file in an ignored directory",
+			patterns: "docs\n!docs/keep.md",
+			ignored:  []string{"docs/README.md", "docs/sub/keep.md"},
+			kept:     []string{"docs/keep.md"},
+		},
+		{
+			name:     "character classes and escapes",
+			patterns: "file[0-9].txt\nlog[!a].txt\n\\*.bak",
+			ignored:  []string{"file1.txt", "logb.txt", "*.bak"},
+			kept:     []string{"filea.txt", "loga.txt", "old.bak"},
+		},
+		{
+			name:     "comments and empty lines are skipped",
+			patterns: "# *.go\n\n   \nvendor",
+			ignored:  []string{"vendor/a/b.go"},
+			kept:     []string{"main.go", "# *.go"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			context := t.TempDir()
+			err := os.WriteFile(filepath.Join(context, dockerignoreFile), []byte(tc.patterns), 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ignore, err := loadDockerignore(context)
+			if err != nil {
+				t.Fatalf("unexpected error loading the %s: %v", dockerignoreFile, err)
+			}
+
+			for _, rel := range tc.ignored {
+				if !ignore.Match(rel) {
+					t.Errorf("expected %q to be ignored", rel)
+				}
+			}
+			for _, rel := range tc.kept {
+				if ignore.Match(rel) {
+					t.Errorf("expected %q not to be ignored", rel)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadDockerignoreMissing(t *testing.T) {
+	ignore, err := loadDockerignore(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error loading a missing %s: %v", dockerignoreFile, err)
+	}
+
+	if ignore.Match("main.go") {
+		t.Error("expected no file to be ignored without a .dockerignore")
+	}
+}
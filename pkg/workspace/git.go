@@ -0,0 +1,101 @@
+package workspace
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+)
+
+// GitChanges represents work in a git repository
+// that would be lost if the repository was removed
+type GitChanges struct {
+	// The path to the repository
+	Repository string
+	// Files with uncommitted changes, in `git status --porcelain` format
+	Uncommitted []string
+	// Commits that have not been pushed to any remote
+	Unpushed []string
+}
+
+// FindGitChanges searches the provided directory for git repositories
+// and returns the uncommitted and unpushed changes of each repository that has any.
+// Returns an error if any occur during the process
+func FindGitChanges(dir string) ([]GitChanges, error) {
+	changes := []GitChanges{}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("encountered an error finding the `git` executable: %w", err)
+	}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.Name() != ".git" {
+			return nil
+		}
+
+		repo := filepath.Dir(path)
+		repoChanges, err := gitChanges(repo)
+		if err != nil {
+			return err
+		}
+
+		if len(repoChanges.Uncommitted) > 0 || len(repoChanges.Unpushed) > 0 {
+			changes = append(changes, *repoChanges)
+		}
+
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error searching `%s` for git repositories: %w", dir, err)
+	}
+
+	return changes, nil
+}
+
+// gitChanges gets the uncommitted and unpushed changes of a single repository
+func gitChanges(repo string) (*GitChanges, error) {
+	uncommitted, err := runGitCmd(repo, "status", "--porcelain")
+	if err != nil {
+		return nil, fmt.Errorf("encountered an error getting the status of git repository `%s`: %w | out: %s", repo, err, uncommitted)
+	}
+
+	// a repository without any commits has nothing that could be unpushed
+	unpushed := []byte{}
+	if _, err := runGitCmd(repo, "rev-parse", "--verify", "HEAD"); err == nil {
+		unpushed, err = runGitCmd(repo, "log", "--oneline", "--branches", "--not", "--remotes")
+		if err != nil {
+			return nil, fmt.Errorf("encountered an error getting the unpushed commits of git repository `%s`: %w | out: %s", repo, err, unpushed)
+		}
+	}
+
+	return &GitChanges{
+		Repository:  repo,
+		Uncommitted: splitLines(uncommitted),
+		Unpushed:    splitLines(unpushed),
+	}, nil
+}
+
+// runGitCmd is a helper function to run git in the provided repository with the specified args.
+func runGitCmd(repo string, args ...string) ([]byte, error) {
+	return exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput()
+}
+
+func splitLines(out []byte) []string {
+	lines := []string{}
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			lines = append(lines, string(line))
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,135 @@
+package workspace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// imageTagLength is the number of hex characters of the content hash used as the image tag
+const imageTagLength = 12
+
+// ImageHash returns the hash of the contents of the containerfile and build context. Files
+// the .dockerignore of the context ignores are not sent to the container runtime so they
+// are not hashed either. Returns an error if any occur during the process
+func ImageHash(containerfile string, context string) (string, error) {
+	hash := sha256.New()
+
+	err := hashPath(hash, "containerfile", containerfile, nil)
+	if err != nil {
+		return "", err
+	}
+
+	ignore, err := loadDockerignore(context)
+	if err != nil {
+		return "", err
+	}
+
+	err = hashPath(hash, "context", context, ignore)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ImageRef returns the reference to tag an image built for the workspace with the provided
+// name with. The tag is derived from the hash of the containerfile and build context
+// so that an image only needs to be rebuilt when either of them change
+func ImageRef(namespace string, workspaceName string, hash string) string {
+	tag := hash
+	if len(tag) > imageTagLength {
+		tag = tag[:imageTagLength]
+	}
+
+	repository := strings.ToLower(workspaceName)
+	if namespace != "" {
+		repository = fmt.Sprintf("%s/%s", strings.Trim(namespace, "/"), repository)
+	}
+
+	return fmt.Sprintf("%s:%s", repository, tag)
+}
+
+// hashPath writes the contents of the file or directory at the path to the hash.
+// Paths that don't exist locally, such as git repository URLs used as a
+// build context, can't be read so the path itself is written instead. Files in
+// a directory that the ignore patterns match are skipped
+func hashPath(hash io.Writer, kind string, path string, ignore dockerignore) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		fmt.Fprintf(hash, "%s %s\n", kind, path)
+		return nil
+	}
+
+	if !info.IsDir() {
+		fmt.Fprintf(hash, "%s\n", kind)
+		return hashFile(hash, path)
+	}
+
+	fmt.Fprintf(hash, "%s dir\n", kind)
+	err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		rel, err := filepath.Rel(path, file)
+		if err != nil {
+			return err
+		}
+
+		// the .dockerignore itself is always hashed since it changes what is sent
+		if rel != dockerignoreFile && ignore.Match(filepath.ToSlash(rel)) {
+			if d.IsDir() && !ignore.hasExclusions() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(file)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(hash, "link %s %s\n", filepath.ToSlash(rel), target)
+		case d.Type().IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(hash, "file %s %o\n", filepath.ToSlash(rel), info.Mode().Perm())
+			return hashFile(hash, file)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("encountered an error hashing the %s `%s`: %w", kind, path, err)
+	}
+
+	return nil
+}
+
+func hashFile(hash io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("encountered an error opening `%s`: %w", path, err)
+	}
+	defer file.Close()
+
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return fmt.Errorf("encountered an error reading `%s`: %w", path, err)
+	}
+
+	return nil
+}
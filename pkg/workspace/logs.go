@@ -0,0 +1,74 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// LogsDirName is the name of the directory under the workspace
+	// root that the output of `cade up` is logged to
+	LogsDirName = ".logs"
+
+	upLogPrefix = "up-"
+	upLogSuffix = ".log"
+)
+
+// LogsDir returns the directory the logs of the workspace with the provided name are kept in
+func LogsDir(workspaceRoot string, workspaceName string) string {
+	return filepath.Join(workspaceRoot, LogsDirName, workspaceName)
+}
+
+// UpLogPath returns the path of the log of a `cade up` of the workspace started at the provided time
+func UpLogPath(workspaceRoot string, workspaceName string, started time.Time) string {
+	return filepath.Join(LogsDir(workspaceRoot, workspaceName), upLogPrefix+started.UTC().Format(trashTimeFormat)+upLogSuffix)
+}
+
+// ListUpLogs returns the paths of the logs of `cade up` for the workspace,
+// newest first. Returns an error if any occur during the process
+func ListUpLogs(workspaceRoot string, workspaceName string) ([]string, error) {
+	logs := []string{}
+	logsDir := LogsDir(workspaceRoot, workspaceName)
+
+	entries, err := os.ReadDir(logsDir)
+	if os.IsNotExist(err) {
+		return logs, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("encountered an error reading the logs directory `%s`: %w", logsDir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), upLogPrefix) || !strings.HasSuffix(entry.Name(), upLogSuffix) {
+			continue
+		}
+
+		logs = append(logs, filepath.Join(logsDir, entry.Name()))
+	}
+
+	// the timestamps in the names sort in the order the logs were created
+	sort.Sort(sort.Reverse(sort.StringSlice(logs)))
+
+	return logs, nil
+}
+
+// PruneUpLogs removes all but the newest logs of `cade up` for the workspace.
+// Returns an error if any occur during the process
+func PruneUpLogs(workspaceRoot string, workspaceName string, keep int) error {
+	logs, err := ListUpLogs(workspaceRoot, workspaceName)
+	if err != nil {
+		return err
+	}
+
+	for i := keep; i < len(logs); i++ {
+		err = os.Remove(logs[i])
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("encountered an error removing the old log `%s`: %w", logs[i], err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// RuntimeDir returns the directory on the host for the sockets and files of the
+// workspace with the provided name that only exist while the workspace does.
+// It is under $XDG_RUNTIME_DIR when it is set and a directory of the current user in the
+// temporary directory otherwise
+func RuntimeDir(workspaceName string) string {
+	base := os.Getenv("XDG_RUNTIME_DIR")
+	if base != "" {
+		return filepath.Join(base, "cade", workspaceName)
+	}
+
+	// the temporary directory can be shared with the other users of the host
+	name := "cade"
+	if uid := os.Getuid(); uid >= 0 {
+		name = fmt.Sprintf("cade-%d", uid)
+	}
+
+	return filepath.Join(os.TempDir(), name, workspaceName)
+}
+
+// EnsureRuntimeDir creates the runtime directory of the workspace with the provided name
+// and returns its path. It and the cade directory it is in can only be accessed by the current
+// user. Returns an error if any occur during the process, including if either is owned by another user
+func EnsureRuntimeDir(workspaceName string) (string, error) {
+	runtimeDir := RuntimeDir(workspaceName)
+
+	for _, dir := range []string{filepath.Dir(runtimeDir), runtimeDir} {
+		err := os.Mkdir(dir, 0700)
+		if err != nil && !os.IsExist(err) {
+			return "", fmt.Errorf("encountered an error creating the directory `%s`: %w", dir, err)
+		}
+
+		info, err := os.Lstat(dir)
+		if err != nil {
+			return "", fmt.Errorf("encountered an error checking the directory `%s`: %w", dir, err)
+		}
+
+		if !info.IsDir() {
+			return "", fmt.Errorf("`%s` is not a directory", dir)
+		}
+
+		// only the owner of the directory can change its permissions
+		err = os.Chmod(dir, 0700)
+		if err != nil {
+			return "", fmt.Errorf("encountered an error restricting the permissions of the directory `%s`, it may be owned by another user: %w", dir, err)
+		}
+	}
+
+	return runtimeDir, nil
+}
@@ -0,0 +1,144 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// TrashDirName is the name of the directory under the workspace
+	// root that removed workspace directories are moved to
+	TrashDirName = ".trash"
+
+	trashTimeFormat = "20060102T150405Z"
+)
+
+// TrashEntry represents a workspace directory that has been moved to the trash
+type TrashEntry struct {
+	// The name of the workspace
+	Workspace string
+	// The path to the directory in the trash
+	Path string
+	// When the directory was moved to the trash
+	Trashed time.Time
+}
+
+// Trash moves the workspace directory into the trash directory under the workspace root.
+// Returns the path the directory was moved to and an error if any occur during the process
+func Trash(workspaceRoot string, workspaceName string) (string, error) {
+	trashDir := filepath.Join(workspaceRoot, TrashDirName)
+	err := os.MkdirAll(trashDir, 0777)
+	if err != nil {
+		return "", fmt.Errorf("encountered an error ensuring the trash directory `%s` exists: %w", trashDir, err)
+	}
+
+	trashPath := filepath.Join(trashDir, fmt.Sprintf("%s.%s", workspaceName, time.Now().UTC().Format(trashTimeFormat)))
+	err = os.Rename(filepath.Join(workspaceRoot, workspaceName), trashPath)
+	if err != nil {
+		return "", fmt.Errorf("encountered an error moving the workspace directory to the trash: %w", err)
+	}
+
+	return trashPath, nil
+}
+
+// ListTrash returns the entries in the trash directory under the workspace root,
+// newest first. Returns an error if any occur during the process
+func ListTrash(workspaceRoot string) ([]TrashEntry, error) {
+	entries := []TrashEntry{}
+	trashDir := filepath.Join(workspaceRoot, TrashDirName)
+
+	dirEntries, err := os.ReadDir(trashDir)
+	if os.IsNotExist(err) {
+		return entries, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("encountered an error reading the trash directory `%s`: %w", trashDir, err)
+	}
+
+	for _, dirEntry := range dirEntries {
+		sep := strings.LastIndex(dirEntry.Name(), ".")
+		if !dirEntry.IsDir() || sep < 0 {
+			continue
+		}
+
+		trashed, err := time.Parse(trashTimeFormat, dirEntry.Name()[sep+1:])
+		if err != nil {
+			continue
+		}
+
+		entries = append(entries, TrashEntry{
+			Workspace: dirEntry.Name()[:sep],
+			Path:      filepath.Join(trashDir, dirEntry.Name()),
+			Trashed:   trashed,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Trashed.After(entries[j].Trashed)
+	})
+
+	return entries, nil
+}
+
+// Restore moves the most recently trashed directory of the workspace back
+// to its working directory. Entries older than the retention are not restored.
+// Returns the path it was restored to and an error if any occur during the process
+func Restore(workspaceRoot string, workspaceName string, retention time.Duration) (string, error) {
+	entries, err := ListTrash(workspaceRoot)
+	if err != nil {
+		return "", err
+	}
+
+	workspaceDir := filepath.Join(workspaceRoot, workspaceName)
+	if _, err := os.Stat(workspaceDir); err == nil {
+		return "", fmt.Errorf("the workspace directory `%s` already exists", workspaceDir)
+	}
+
+	for _, entry := range entries {
+		if entry.Workspace != workspaceName {
+			continue
+		}
+
+		if time.Since(entry.Trashed) > retention {
+			return "", fmt.Errorf("the workspace directory was moved to the trash on %s which is outside the retention window of %s", entry.Trashed.Local().Format(time.RFC1123), retention)
+		}
+
+		err = os.Rename(entry.Path, workspaceDir)
+		if err != nil {
+			return "", fmt.Errorf("encountered an error restoring the workspace directory from the trash: %w", err)
+		}
+
+		return workspaceDir, nil
+	}
+
+	return "", fmt.Errorf("no workspace directory for workspace %q found in the trash", workspaceName)
+}
+
+// PurgeTrash permanently removes trash entries older than the retention.
+// Returns the removed entries and an error if any occur during the process
+func PurgeTrash(workspaceRoot string, retention time.Duration) ([]TrashEntry, error) {
+	purged := []TrashEntry{}
+
+	entries, err := ListTrash(workspaceRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if time.Since(entry.Trashed) <= retention {
+			continue
+		}
+
+		err = os.RemoveAll(entry.Path)
+		if err != nil {
+			return purged, fmt.Errorf("encountered an error removing `%s` from the trash: %w", entry.Path, err)
+		}
+
+		purged = append(purged, entry)
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,145 @@
+package workspace
+
+import "strings"
+
+const (
+	// ContainerPrefix is the prefix of the name of every workspace container
+	ContainerPrefix = "cade-workspace-"
+
+	// ServiceContainerPrefix is the prefix of the name of every workspace service container
+	ServiceContainerPrefix = "cade-service-"
+
+	// NetworkPrefix is the prefix of the name of the networks cade creates for workspaces
+	NetworkPrefix = "cade-"
+
+	// ServiceLabel is the label containing the name of the
+	// workspace service a container was created for
+	ServiceLabel = "cade.service"
+
+	// ManagedLabel is the label applied to every
+	// image, container, volume and network created by cade
+	ManagedLabel = "cade.managed"
+
+	// WorkspaceLabel is the label containing the name of the workspace
+	// an image, container or volume was created for
+	WorkspaceLabel = "cade.workspace"
+
+	// ImageLabel is the label containing the reference
+	// of the image a workspace container was created from
+	ImageLabel = "cade.image"
+
+	// ImageDigestLabel is the label containing the digest of the
+	// image when the workspace container was created from it
+	ImageDigestLabel = "cade.image.digest"
+
+	// ImageHashLabel is the label containing the hash of the containerfile and
+	// build context an image was built from. See ImageHash
+	ImageHashLabel = "cade.image.hash"
+
+	// ProfileLabel is the label containing the config profile
+	// a workspace container was created with
+	ProfileLabel = "cade.profile"
+
+	// UserRemapLabel is the label set on workspace containers created from an image
+	// built to give the image user the UID and GID of the host user
+	UserRemapLabel = "cade.user.remap"
+
+	// SSHAgentLabel is the label containing the path of the host SSH
+	// agent socket that was forwarded into a workspace container
+	SSHAgentLabel = "cade.ssh-agent"
+
+	// GitCredentialsLabel is the label set on workspace containers
+	// that git credentials are forwarded into
+	GitCredentialsLabel = "cade.git-credentials"
+
+	// GitCredentialChangesLabel is the label set on workspace containers that
+	// can store and erase credentials in the git credential helpers of the host
+	GitCredentialChangesLabel = "cade.git-credentials.changes"
+
+	// ComposeProjectLabel is the label containing the name of the compose
+	// project a workspace container was created by
+	ComposeProjectLabel = "cade.compose.project"
+
+	// SyncLabel is the label containing the path of the working directory in workspace
+	// containers whose working directory is a volume synced with the host
+	SyncLabel = "cade.sync"
+
+	// SyncIgnoreLabel is the label containing the JSON list of the
+	// patterns of the files that are not synced with the host
+	SyncIgnoreLabel = "cade.sync.ignore"
+
+	// HealthcheckLabel is the label containing the JSON of the health check from the workspace
+	// config. Unlike the health check of the container, it doesn't include one from the image
+	HealthcheckLabel = "cade.healthcheck"
+
+	// ComposeProjectPrefix is the prefix of the name of the compose projects of workspaces
+	ComposeProjectPrefix = "cade-"
+
+	// workdirVolumeSuffix is the suffix of the volumes that
+	// hold the synced working directories of workspaces
+	workdirVolumeSuffix = "-workdir"
+
+	// copierSuffix is the suffix of the temporary containers
+	// used to copy files from a workspace image to the host
+	copierSuffix = "-copier"
+)
+
+// ContainerName returns the name of the container for the workspace with the provided name
+func ContainerName(workspaceName string) string {
+	return ContainerPrefix + workspaceName
+}
+
+// ServiceContainerName returns the name of the container for the service of the workspace
+func ServiceContainerName(workspaceName string, serviceName string) string {
+	return ServiceContainerPrefix + workspaceName + "-" + serviceName
+}
+
+// NetworkName returns the name of the dedicated network of the workspace
+func NetworkName(workspaceName string) string {
+	return NetworkPrefix + workspaceName
+}
+
+// WorkdirVolumeName returns the name of the volume that holds the
+// working directory of the workspace when it is synced with the host
+func WorkdirVolumeName(workspaceName string) string {
+	return ContainerPrefix + workspaceName + workdirVolumeSuffix
+}
+
+// ComposeProjectName returns the name of the compose project of the workspace.
+// Compose project names must be lowercase
+func ComposeProjectName(workspaceName string) string {
+	return strings.ToLower(ComposeProjectPrefix + workspaceName)
+}
+
+// Labels returns the labels that identify resources
+// created for the workspace with the provided name
+func Labels(workspaceName string) map[string]string {
+	return map[string]string{
+		ManagedLabel:   "true",
+		WorkspaceLabel: workspaceName,
+	}
+}
+
+// ManagedLabels returns the labels that identify
+// resources created by cade for any workspace
+func ManagedLabels() map[string]string {
+	return map[string]string{
+		ManagedLabel: "true",
+	}
+}
+
+// IsCopier returns whether or not the container with the provided name is a temporary
+// container used to copy files from a workspace image to the host
+func IsCopier(containerName string) bool {
+	return strings.HasPrefix(containerName, ContainerPrefix) && strings.HasSuffix(containerName, copierSuffix)
+}
+
+// NameFromContainer returns the name of the workspace that the container with
+// the provided name belongs to and whether or not it is a workspace container
+func NameFromContainer(containerName string) (string, bool) {
+	if !strings.HasPrefix(containerName, ContainerPrefix) || IsCopier(containerName) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(containerName, ContainerPrefix), true
+}